@@ -1,9 +1,210 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lk153/import-gsheet/internal/config"
 	"github.com/lk153/import-gsheet/internal/imports"
 )
 
+const usage = `Usage: cli <command> [flags]
+
+Commands:
+  import     read the sheet rows and update or create the suppliers in the database
+  validate   read the sheet rows and check them without writing to the database
+  diff       compare the sheet rows with the suppliers in the database
+  export     write the suppliers of the database to the sheet, in the layout import reads
+
+The rows are read from a Google sheet, or from a CSV or XLSX file with --source csv|xlsx --file <path>.
+export writes to a Google sheet or, with --source csv --file <path>, to a CSV file.
+
+Run "cli <command> -h" to list the flags of a command.
+`
+
 func main() {
-	imports.Import()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "import":
+		err = runImport(args)
+	case "validate":
+		err = runValidate(args)
+	case "diff":
+		err = runDiff(args)
+	case "export":
+		err = runExport(args)
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+		os.Exit(1)
+	}
+}
+
+// commonFlags registers the flags shared by every command and returns the options they fill in
+func commonFlags(fs *flag.FlagSet) (opts *imports.Options, configFile *string) {
+	opts = &imports.Options{}
+	fs.StringVar(&opts.Source, "source", imports.SourceGSheet, "where to read (or export) the rows: gsheet, csv or xlsx")
+	fs.StringVar(&opts.SpreadsheetID, "spreadsheet-id", "", "ID of the Google spreadsheet to read (required for gsheet)")
+	fs.StringVar(&opts.Sheet, "sheet", "To Update on DB", "name of the sheet/tab to read (xlsx: default the first sheet)")
+	fs.StringVar(&opts.Range, "range", "", "A1 range of the rows to read within the sheet (default: the rows below --header-row, as wide as the header row; export: as the mapping)")
+	fs.IntVar(&opts.HeaderRow, "header-row", 2, "sheet row number of the column headers")
+	fs.StringVar(&opts.File, "file", "", "CSV or XLSX file to read (required for csv and xlsx)")
+	fs.StringVar(&opts.Delimiter, "delimiter", ",", "field delimiter of the CSV file, a single character or tab")
+	fs.StringVar(&opts.Encoding, "encoding", "utf-8", "encoding of the CSV file, e.g. utf-8, gbk, gb18030, big5 or utf-16le")
+	fs.StringVar(&opts.MappingFile, "mapping", "", "yaml/json file mapping the sheet columns to table fields (default: built-in layout)")
+	configFile = fs.String("config", "", "yaml config file, e.g. local.env.yaml")
+	return
+}
+
+// insertWithoutWriteBackFlag registers the flag allowing new suppliers to be created from a CSV or XLSX file
+func insertWithoutWriteBackFlag(fs *flag.FlagSet, opts *imports.Options) {
+	fs.BoolVar(&opts.InsertWithoutWriteBack, "insert-without-write-back", false,
+		"create the suppliers of the rows without supplier ID from a CSV or XLSX file, whose new ID cannot be written back: a rerun creates them again")
+}
+
+// parseFlags parses the command flags; a CSV or XLSX file has its header on the first line unless told otherwise.
+// It fails on the arguments left over, such as a config file passed without --config, and on the flags after them
+// that are not parsed.
+func parseFlags(fs *flag.FlagSet, args []string, opts *imports.Options) error {
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument(s) %q: %s takes flags only, e.g. --config local.env.yaml", fs.Args(), fs.Name())
+	}
+
+	if opts.Source != imports.SourceCSV && opts.Source != imports.SourceXLSX {
+		return nil
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["sheet"] {
+		opts.Sheet = ""
+	}
+	if !set["header-row"] {
+		opts.HeaderRow = 1
+	}
+
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	opts, configFile := commonFlags(fs)
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "print the planned changes per supplier and roll them back instead of committing")
+	fs.StringVar(&opts.ReportFile, "report", "", "write the run report of every processed row to this file")
+	fs.StringVar(&opts.ReportFormat, "report-format", "", "json or csv (default: the extension of --report)")
+	fs.StringVar(&opts.Operator, "operator", currentUser(), "recorded as created_by/deleted_by of the suppliers the run creates or deletes")
+	asOf := fs.String("as-of", "", "UTC time the sheet was prepared at, for the rows without As Of cell; rows of suppliers updated since are refused")
+	fs.BoolVar(&opts.Force, "force", false, "write the rows of the suppliers updated after their as-of time anyway")
+	insertWithoutWriteBackFlag(fs, opts)
+	if err := parseFlags(fs, args, opts); err != nil {
+		return err
+	}
+
+	var err error
+	if opts.AsOf, err = parseTime("as-of", *asOf); err != nil {
+		return err
+	}
+
+	config.Load(*configFile)
+	return imports.Import(*opts)
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	opts, configFile := commonFlags(fs)
+	insertWithoutWriteBackFlag(fs, opts)
+	if err := parseFlags(fs, args, opts); err != nil {
+		return err
+	}
+
+	config.Load(*configFile)
+	return imports.Validate(*opts)
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	opts, configFile := commonFlags(fs)
+	fs.StringVar(&opts.ReportFile, "output", "", "write the differences to this file instead of stdout")
+	fs.StringVar(&opts.ReportFormat, "format", "", "text or json (default: json for a .json --output, text otherwise)")
+	if err := parseFlags(fs, args, opts); err != nil {
+		return err
+	}
+
+	config.Load(*configFile)
+	return imports.Diff(*opts)
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	opts, configFile := commonFlags(fs)
+	ids := fs.String("ids", "", "comma separated IDs of the suppliers to export")
+	status := fs.String("status", "", "export the suppliers with this status")
+	category := fs.String("category", "", "export the suppliers assigned to this category")
+	updatedSince := fs.String("updated-since", "", "export the suppliers created or updated since this date, e.g. 2024-01-31 or \"2024-01-31 08:00:00\"")
+	if err := parseFlags(fs, args, opts); err != nil {
+		return err
+	}
+
+	filter := imports.ExportFilter{Status: *status, Category: *category}
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+
+		i, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid supplier ID %q", id)
+		}
+		filter.IDs = append(filter.IDs, i)
+	}
+
+	var err error
+	if filter.UpdatedSince, err = parseTime("updated-since", *updatedSince); err != nil {
+		return err
+	}
+
+	config.Load(*configFile)
+	return imports.Export(*opts, filter)
+}
+
+// parseTime parses the time flag, a date or a date and time in UTC; the zero time when it is empty
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.DateTime} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --%s %q, expected 2006-01-02 or 2006-01-02 15:04:05", name, value)
+}
+
+// currentUser returns the name of the OS user running the command
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+
+	return "import-gsheet"
 }
@@ -10,23 +10,27 @@ import (
 )
 
 var (
-	SDBEnv SDBStrEnv
-	cfg    libconfig.Config
-	once   sync.Once
+	SDBEnv     SDBStrEnv
+	cfg        libconfig.Config
+	configFile string
+	once       sync.Once
 )
 
 type SDBStrEnv struct {
 	libenv.NVBaseEnv
 }
 
-func init() {
-	GetCfg()
+// Load sets the yaml file the configuration is read from and loads it.
+// It must be called before the first GetCfg call, otherwise the file is ignored.
+func Load(file string) libconfig.Config {
+	configFile = file
+	return GetCfg()
 }
 
 func GetCfg() libconfig.Config {
 	once.Do(func() {
 		SDBEnv = SDBStrEnv{}
-		cfg = libconfig.New()
+		cfg = libconfig.New(configFile)
 		if err := libenv.Init(cfg, &SDBEnv); err != nil {
 			log.Err(err).Msg("Error while initializing environment variables")
 			panic(err)
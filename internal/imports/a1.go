@@ -0,0 +1,75 @@
+package imports
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var a1RangeRegex = regexp.MustCompile(`^([A-Za-z]+)(\d*)(:([A-Za-z]*)(\d*))?$`)
+
+// a1Range is a parsed A1 range without the sheet name, e.g. A3:AR or A2:2.
+// Rows are 1-based, EndRow is 0 when the range has no last row and EndCol is -1 when it has no last column.
+type a1Range struct {
+	StartCol int
+	StartRow int
+	EndCol   int
+	EndRow   int
+}
+
+func parseA1Range(rng string) (r a1Range, err error) {
+	m := a1RangeRegex.FindStringSubmatch(strings.TrimSpace(rng))
+	if m == nil {
+		return r, fmt.Errorf("invalid A1 range %q", rng)
+	}
+
+	r.StartCol = columnIndex(m[1])
+	r.StartRow = 1
+	if m[2] != "" {
+		r.StartRow, _ = strconv.Atoi(m[2])
+	}
+
+	switch {
+	case m[3] == "":
+		r.EndCol, r.EndRow = r.StartCol, r.StartRow
+	case m[4] == "" && m[5] == "":
+		return r, fmt.Errorf("invalid A1 range %q", rng)
+	case m[4] == "":
+		r.EndCol = -1
+	default:
+		r.EndCol = columnIndex(m[4])
+	}
+	if m[5] != "" {
+		r.EndRow, _ = strconv.Atoi(m[5])
+	}
+
+	if r.StartRow == 0 || (r.EndCol >= 0 && r.EndCol < r.StartCol) || (r.EndRow != 0 && r.EndRow < r.StartRow) {
+		return r, fmt.Errorf("invalid A1 range %q", rng)
+	}
+
+	return r, nil
+}
+
+// contains reports whether the 0-based column index is within the columns of the range
+func (r a1Range) contains(col int) bool {
+	return col >= r.StartCol && (r.EndCol < 0 || col <= r.EndCol)
+}
+
+// columnIndex converts a column letter to its 0-based index, A => 0, AR => 43
+func columnIndex(name string) (idx int) {
+	for _, c := range strings.ToUpper(name) {
+		idx = idx*26 + int(c-'A') + 1
+	}
+
+	return idx - 1
+}
+
+// columnName converts a 0-based column index to its letter, 0 => A, 43 => AR
+func columnName(idx int) (name string) {
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		name = string(rune('A'+(idx-1)%26)) + name
+	}
+
+	return
+}
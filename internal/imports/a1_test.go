@@ -0,0 +1,48 @@
+package imports
+
+import "testing"
+
+func TestParseA1Range(t *testing.T) {
+	tests := []struct {
+		rng     string
+		want    a1Range
+		wantErr bool
+	}{
+		{rng: "A3", want: a1Range{StartCol: 0, StartRow: 3, EndCol: 0, EndRow: 3}},
+		{rng: "AR", want: a1Range{StartCol: 43, StartRow: 1, EndCol: 43, EndRow: 1}},
+		{rng: "A3:BA", want: a1Range{StartCol: 0, StartRow: 3, EndCol: 52, EndRow: 0}},
+		{rng: "b3:ar10", want: a1Range{StartCol: 1, StartRow: 3, EndCol: 43, EndRow: 10}},
+		{rng: " A3:BA10 ", want: a1Range{StartCol: 0, StartRow: 3, EndCol: 52, EndRow: 10}},
+		{rng: "A2:2", want: a1Range{StartCol: 0, StartRow: 2, EndCol: -1, EndRow: 2}},
+		{rng: "BA3:A", wantErr: true},
+		{rng: "A10:B3", wantErr: true},
+		{rng: "A3:", wantErr: true},
+		{rng: "A0:B", wantErr: true},
+		{rng: "3:3", wantErr: true},
+		{rng: "'To Update on DB'!A3", wantErr: true},
+		{rng: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			got, err := parseA1Range(tt.rng)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseA1Range(%q) error = %v, want error %v", tt.rng, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseA1Range(%q) = %+v, want %+v", tt.rng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for name, idx := range map[string]int{"A": 0, "Z": 25, "AA": 26, "AR": 43, "BA": 52, "ZZ": 701, "AAA": 702} {
+		if got := columnName(idx); got != name {
+			t.Errorf("columnName(%d) = %s, want %s", idx, got, name)
+		}
+		if got := columnIndex(name); got != idx {
+			t.Errorf("columnIndex(%s) = %d, want %d", name, got, idx)
+		}
+	}
+}
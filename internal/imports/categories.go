@@ -0,0 +1,218 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+const tableSupplierCategories = "supplier_categories"
+
+// categories resolves the category names of the sheet, a supplier can only be assigned to leaf categories
+type categories struct {
+	// leaves and parents are keyed by the lower-cased category name
+	leaves  map[string]uint
+	parents map[string]bool
+}
+
+func loadCategories(q sqlx.Queryer) (*categories, error) {
+	leaves, err := getMostChildCateMap(q)
+	if err != nil {
+		return nil, err
+	}
+
+	parents, err := getParentCateNames(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &categories{leaves: leaves, parents: parents}, nil
+}
+
+// resolve returns the sorted IDs of the comma separated category names of the cell, none for a blank cell.
+// Names containing commas are matched as a whole, the longest known name first.
+func (c *categories) resolve(cell string) (ids []uint, err error) {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return nil, nil
+	}
+
+	var errs []error
+	seen := map[uint]bool{}
+	parts := strings.Split(cell, ",")
+	for i := 0; i < len(parts); i++ {
+		name := strings.TrimSpace(parts[i])
+		for j := len(parts); j > i+1; j-- {
+			joined := strings.TrimSpace(strings.Join(parts[i:j], ","))
+			if _, ok := c.leaves[strings.ToLower(joined)]; ok || c.parents[strings.ToLower(joined)] {
+				name, i = joined, j-1
+				break
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		id, ok := c.leaves[strings.ToLower(name)]
+		switch {
+		case ok && !seen[id]:
+			seen[id] = true
+			ids = append(ids, id)
+		case ok:
+		case c.parents[strings.ToLower(name)]:
+			errs = append(errs, fmt.Errorf("category %q is not a leaf category", name))
+		default:
+			errs = append(errs, fmt.Errorf("unknown category %q", name))
+		}
+	}
+
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// getMostChildCateMap returns the IDs of the leaf categories by lower-cased name
+func getMostChildCateMap(q sqlx.Queryer) (map[string]uint, error) {
+	type Cate struct {
+		Id   uint   `db:"category_id"`
+		Name string `db:"name"`
+	}
+
+	// the root categories have no parent, NOT IN would match nothing with a NULL in the list
+	var cates []Cate
+	err := sqlx.Select(q, &cates, `SELECT c.category_id, c.name FROM categories c
+		WHERE c.category_id NOT IN (
+			SELECT c2.parent_id FROM categories c2
+			WHERE c2.deleted_at IS NULL AND c2.parent_id IS NOT NULL
+			GROUP BY c2.parent_id
+		) AND c.deleted_at IS NULL;`)
+	if err != nil {
+		return nil, fmt.Errorf("getMostChildCateMap: %w", err)
+	}
+
+	cateMap := make(map[string]uint, len(cates))
+	for _, cate := range cates {
+		cateMap[strings.ToLower(strings.TrimSpace(cate.Name))] = cate.Id
+	}
+
+	return cateMap, nil
+}
+
+// getParentCateNames returns the lower-cased names of the categories having sub categories
+func getParentCateNames(q sqlx.Queryer) (map[string]bool, error) {
+	var names []string
+	err := sqlx.Select(q, &names, `SELECT DISTINCT p.name FROM categories p
+		JOIN categories c ON c.parent_id = p.category_id AND c.deleted_at IS NULL
+		WHERE p.deleted_at IS NULL;`)
+	if err != nil {
+		return nil, fmt.Errorf("getParentCateNames: %w", err)
+	}
+
+	parents := make(map[string]bool, len(names))
+	for _, name := range names {
+		parents[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	return parents, nil
+}
+
+// syncSupplierCategories inserts and soft-deletes supplier_categories so the supplier ends up with exactly the
+// categoryIDs, and returns the category IDs it had before
+func syncSupplierCategories(tx *sqlx.Tx, supplierID int64, categoryIDs []uint, result *report.Row) (current []uint, err error) {
+	err = tx.Select(&current, `SELECT category_id FROM supplier_categories
+		WHERE supplier_id = ? AND deleted_at IS NULL
+		ORDER BY category_id;`, supplierID)
+	if err != nil {
+		return nil, fmt.Errorf("syncSupplierCategories: %w", err)
+	}
+
+	toDelete, toInsert := diffIDs(current, categoryIDs), diffIDs(categoryIDs, current)
+	now := time.Now().UTC()
+	if len(toDelete) > 0 {
+		query, args, err := sqlx.In(`UPDATE supplier_categories SET deleted_at = ?
+			WHERE supplier_id = ? AND category_id IN (?) AND deleted_at IS NULL`, now, supplierID, toDelete)
+		if err != nil {
+			return nil, fmt.Errorf("syncSupplierCategories: %w", err)
+		}
+
+		affected, err := execAffected(tx, "syncSupplierCategories", tx.Rebind(query), args...)
+		if err != nil {
+			return nil, err
+		}
+		result.AddAction(tableSupplierCategories, report.ActionDelete, affected, []string{`deleted_at`})
+	}
+
+	if len(toInsert) > 0 {
+		var inserted int64
+		for _, categoryID := range toInsert {
+			affected, err := execAffected(tx, "syncSupplierCategories",
+				`INSERT INTO supplier_categories (supplier_id, category_id, created_at) VALUES (?, ?, ?)`, supplierID, categoryID, now)
+			if err != nil {
+				return nil, err
+			}
+			inserted += affected
+		}
+		result.AddAction(tableSupplierCategories, report.ActionInsert, inserted, []string{`supplier_id`, `category_id`, `created_at`})
+	}
+
+	return current, nil
+}
+
+// execAffected runs the query and returns the number of rows it affected
+func execAffected(tx *sqlx.Tx, name, query string, args ...any) (affected int64, err error) {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if affected, err = result.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("%s: RowsAffected: %w", name, err)
+	}
+
+	return affected, nil
+}
+
+// diffIDs returns the IDs of a that are not in b
+func diffIDs(a, b []uint) (ids []uint) {
+	in := make(map[uint]bool, len(b))
+	for _, id := range b {
+		in[id] = true
+	}
+
+	for _, id := range a {
+		if !in[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// categoryChanges returns the change of the category set of the supplier, if any
+func categoryChanges(before, after []uint) []report.Change {
+	b, a := joinIDs(before), joinIDs(after)
+	if a == b {
+		return nil
+	}
+
+	return []report.Change{{Table: tableSupplierCategories, Field: "category_id", Before: b, After: a}}
+}
+
+func joinIDs(ids []uint) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.FormatUint(uint64(id), 10))
+	}
+
+	return strings.Join(s, ",")
+}
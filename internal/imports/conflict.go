@@ -0,0 +1,82 @@
+package imports
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+// conflictQueries lock the records of the supplier and select their updated_at, by table
+var conflictQueries = []struct{ table, query string }{
+	{mapping.TableSuppliers, `SELECT updated_at FROM suppliers WHERE id = ? AND deleted_at IS NULL FOR UPDATE;`},
+	{mapping.TableSupplierDetails, `SELECT updated_at FROM supplier_details WHERE supplier_id = ? AND deleted_at IS NULL FOR UPDATE;`},
+	{mapping.TableBankAccountDetails, `SELECT updated_at FROM bank_account_details WHERE supplier_id = ? AND deleted_at IS NULL FOR UPDATE;`},
+}
+
+// rowAsOf returns the time the row was prepared at: its as-of cell, or asOf when the cell is blank or missing.
+// The zero time means the row is not checked for conflicts.
+func rowAsOf(row sheetRow, asOf time.Time) (time.Time, error) {
+	col, ok := row.mapping.Column(colAsOf)
+	cell := strings.TrimSpace(row.get(colAsOf))
+	if !ok || cell == "" {
+		return asOf, nil
+	}
+
+	value, err := col.Parse(cell)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q: %w", col.Header, err)
+	}
+
+	t, ok := value.(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%q: %s column is not a %s column", col.Header, colAsOf, mapping.TypeDate)
+	}
+
+	return t, nil
+}
+
+// checkConflicts locks the records of the supplier for the rest of the transaction and returns the ones updated
+// after asOf, nothing when asOf is zero. The conflicts are recorded on result.
+func checkConflicts(tx *sqlx.Tx, supplierID int64, asOf time.Time, result *report.Row) (conflicts []string, err error) {
+	if asOf.IsZero() {
+		return nil, nil
+	}
+
+	for _, c := range conflictQueries {
+		var updatedAt []sql.NullTime
+		if err = tx.Select(&updatedAt, c.query, supplierID); err != nil {
+			return nil, fmt.Errorf("checkConflicts: %s: %w", c.table, err)
+		}
+
+		for _, t := range updatedAt {
+			if t.Valid && t.Time.After(asOf) {
+				conflicts = append(conflicts, fmt.Sprintf("%s updated at %s after %s",
+					c.table, t.Time.UTC().Format(time.DateTime), asOf.Format(time.DateTime)))
+			}
+		}
+	}
+
+	result.Conflicts = conflicts
+	return conflicts, nil
+}
+
+// refuseConflicts checks the supplier for conflicts and, unless the run is forced, rolls the transaction back
+// and returns a *RowError when it has any
+func refuseConflicts(tx *sqlx.Tx, supplierID int64, asOf time.Time, opts Options, result *report.Row) error {
+	conflicts, err := checkConflicts(tx, supplierID, asOf, result)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Err: err})
+	}
+
+	if len(conflicts) > 0 && !opts.Force {
+		return rollback(tx, result, &RowError{Stage: report.StageConflict, Err: fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, ", "))})
+	}
+
+	return nil
+}
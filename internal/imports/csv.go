@@ -0,0 +1,101 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+func newCSVSource(opts Options) (RowSource, error) {
+	if opts.File == "" {
+		return nil, errors.New("CSV file is empty")
+	}
+
+	comma, err := csvDelimiter(opts.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("open CSV file: %w", err)
+	}
+	defer f.Close()
+
+	r, err := decodeReader(f, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV file %s: %w", opts.File, err)
+	}
+
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+
+	return fileSource{records: records}, nil
+}
+
+// csvDelimiter returns the field delimiter, a single character or "tab"
+func csvDelimiter(delimiter string) (rune, error) {
+	switch delimiter {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	comma, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || comma == '"' || comma == '\r' || comma == '\n' || comma == utf8.RuneError {
+		return 0, fmt.Errorf("invalid CSV delimiter %q", delimiter)
+	}
+
+	return comma, nil
+}
+
+// decodeReader converts the file from the encoding, e.g. gbk, gb18030, big5 or utf-16le, to UTF-8
+func decodeReader(r io.Reader, encoding string) (io.Reader, error) {
+	if encoding == "" || strings.EqualFold(encoding, "utf-8") || strings.EqualFold(encoding, "utf8") {
+		return r, nil
+	}
+
+	enc, err := htmlindex.Get(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+
+	return transform.NewReader(r, enc.NewDecoder()), nil
+}
+
+// encodeBytes converts the UTF-8 text to the encoding, the reverse of decodeReader
+func encodeBytes(b []byte, encoding string) ([]byte, error) {
+	if encoding == "" || strings.EqualFold(encoding, "utf-8") || strings.EqualFold(encoding, "utf8") {
+		return b, nil
+	}
+
+	enc, err := htmlindex.Get(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+
+	b, err = enc.NewEncoder().Bytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("encode to %s: %w", encoding, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,69 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportRowsCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		delimiter string
+		encoding  string
+		want      string
+		wantErr   string
+	}{
+		{name: "comma", text: "Supplier ID,Company Name\n1,Acme Toys\n", want: "Acme Toys"},
+		{name: "semicolon", text: "Supplier ID;Company Name\n1;\"Acme; Toys\"\n", delimiter: ";", want: "Acme; Toys"},
+		{name: "tab", text: "Supplier ID\tCompany Name\n1\tAcme Toys\n", delimiter: "tab", want: "Acme Toys"},
+		{name: "BOM", text: "\ufeffSupplier ID,Company Name\r\n1,Acme Toys\r\n", want: "Acme Toys"},
+		{name: "gbk", text: "Supplier ID,Company Name\n1,深圳玩具\n", encoding: "gbk", want: "深圳玩具"},
+		{name: "gb18030", text: "Supplier ID,Company Name\n1,深圳玩具\n", encoding: "gb18030", want: "深圳玩具"},
+		{name: "utf-16le BOM", text: "\ufeffSupplier ID,Company Name\n1,深圳玩具\n", encoding: "utf-16le", want: "深圳玩具"},
+		{name: "invalid delimiter", text: "Supplier ID,Company Name\n", delimiter: "||", wantErr: `invalid CSV delimiter "||"`},
+		{name: "unknown encoding", text: "Supplier ID,Company Name\n", encoding: "ebcdic", wantErr: `unknown encoding "ebcdic"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := encodeBytes([]byte(tt.text), tt.encoding)
+			if err != nil && tt.wantErr == "" {
+				t.Fatal(err)
+			}
+
+			opts := testOptions(t)
+			opts.Source, opts.HeaderRow, opts.Delimiter, opts.Encoding = SourceCSV, 1, tt.delimiter, tt.encoding
+			opts.File = filepath.Join(t.TempDir(), "suppliers.csv")
+			if err = os.WriteFile(opts.File, b, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			src, err := newCSVSource(opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newCSVSource() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCSVSource() error = %v", err)
+			}
+
+			// the BOM is trimmed from the first cell, not only ignored when the header is matched
+			if header, err := src.ReadRows("A1:B1"); err != nil || len(header) != 1 || header[0][0] != "Supplier ID" {
+				t.Fatalf("header = %q (%v), want Supplier ID first", header, err)
+			}
+
+			dbInstance := newTestDB(t)
+			if err = ImportRows(dbInstance, src, opts); err != nil {
+				t.Fatalf("ImportRows() error = %v", err)
+			}
+			if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != tt.want {
+				t.Errorf("company_name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package imports
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+	"github.com/lk153/import-gsheet/internal/models"
+	"github.com/lk153/import-gsheet/internal/report"
+	"github.com/lk153/import-gsheet/internal/validator"
+)
+
+/*Actions a row can ask for in the action column, update (or insert without supplier ID) when blank*/
+const (
+	actionUpdate = "update"
+	actionDelete = "delete"
+)
+
+// deleteRequest is the reason of a delete row, checked against validator.DeleteChangeReasons
+type deleteRequest struct {
+	Reason string `db:"delete_reason" validate:"required,customDeleteReason"`
+}
+
+// parseAction returns the action of the row, actionUpdate when the cell is blank
+func parseAction(row sheetRow) (string, error) {
+	switch action := strings.ToLower(strings.TrimSpace(row.get(colAction))); action {
+	case "", actionUpdate:
+		return actionUpdate, nil
+	case actionDelete:
+		return actionDelete, nil
+	default:
+		return "", fmt.Errorf("unknown action %q, expected %s or %s", row.get(colAction), actionUpdate, actionDelete)
+	}
+}
+
+// hasDeleteRows reports whether one of the rows asks for the delete action
+func hasDeleteRows(rows []sheetRow) bool {
+	for _, row := range rows {
+		if action, err := parseAction(row); err == nil && action == actionDelete && !row.isBlank() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDeleteReason returns the reason of a delete row, it must be one of validator.DeleteChangeReasons
+func parseDeleteReason(row sheetRow) (string, error) {
+	req := &deleteRequest{Reason: strings.TrimSpace(row.get(colDeleteReason))}
+	if err := models.Validate(req); err != nil {
+		return "", fmt.Errorf("invalid delete reason %q, expected one of: %s", req.Reason, strings.Join(validator.DeleteChangeReasons, ", "))
+	}
+
+	return req.Reason, nil
+}
+
+// validateDelete runs the checks of BulkDelete on the row without touching the database
+func validateDelete(row sheetRow) error {
+	if _, err := parseSupplierID(row); err != nil {
+		return err
+	}
+
+	_, err := parseDeleteReason(row)
+	return err
+}
+
+// BulkDelete soft-deletes the supplier of the row together with its supplier_details and bank_account_details
+// in a single transaction. deleted_by is the operator of the run; the reason has no column and is kept in the report,
+// which Import requires for the runs deleting suppliers.
+// Like BulkUpdate it does not delete a supplier updated after the as-of time of the row unless the run is forced.
+func BulkDelete(dbInstance DB, row sheetRow, opts Options, result *report.Row) error {
+	supplierID, err := parseSupplierID(row)
+	if err != nil {
+		return &RowError{Stage: report.StageParse, Err: err}
+	}
+	result.SupplierID = supplierID
+
+	reason, err := parseDeleteReason(row)
+	if err != nil {
+		return &RowError{Stage: report.StageValidation, Err: err}
+	}
+	result.DeletedBy, result.DeleteReason = opts.Operator, reason
+
+	asOf, err := rowAsOf(row, opts.AsOf)
+	if err != nil {
+		return &RowError{Stage: report.StageParse, Err: err}
+	}
+
+	deletedAt := sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	supplierBean := &models.Supplier{
+		Id:        supplierID,
+		DeletedAt: deletedAt,
+		DeletedBy: sql.NullString{String: opts.Operator, Valid: opts.Operator != ""},
+	}
+	supplierDetailBean := &models.SupplierDetail{SupplierId: supplierID, DeletedAt: deletedAt}
+	bankAccountBean := &models.BankAccountDetails{SupplierId: supplierID, DeletedAt: deletedAt}
+
+	/*Execute Supplier deletion query on DB*/
+	tx, err := dbInstance.Beginx()
+	if err != nil {
+		return &RowError{Stage: report.StageDB, Err: fmt.Errorf("cannot begin DB transaction: %w", err)}
+	}
+
+	if err = refuseConflicts(tx, supplierID, asOf, opts, result); err != nil {
+		return err
+	}
+
+	before, err := loadCurrentState(tx, supplierID)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Err: err})
+	}
+
+	supplierColumns := []string{`deleted_at`, `deleted_by`}
+	affected, err := execNamed(tx, "execSupplierDelete",
+		`UPDATE suppliers SET deleted_at = :deleted_at, deleted_by = :deleted_by WHERE id = :id AND deleted_at IS NULL`, supplierBean)
+	if err == nil && affected == 0 {
+		err = fmt.Errorf("%w: id %d", ErrSupplierNotFound, supplierID)
+	}
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableSuppliers, Err: err})
+	}
+	result.AddAction(mapping.TableSuppliers, report.ActionDelete, affected, supplierColumns)
+
+	columns := []string{`deleted_at`}
+	supplierDetailAffected, err := execNamed(tx, "execSupplierDetailDelete",
+		`UPDATE supplier_details SET deleted_at = :deleted_at WHERE supplier_id = :supplier_id AND deleted_at IS NULL`, supplierDetailBean)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableSupplierDetails, Err: err})
+	}
+	result.AddAction(mapping.TableSupplierDetails, report.ActionDelete, supplierDetailAffected, columns)
+
+	bankAccountAffected, err := execNamed(tx, "execBankAccountDelete",
+		`UPDATE bank_account_details SET deleted_at = :deleted_at WHERE supplier_id = :supplier_id AND deleted_at IS NULL`, bankAccountBean)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableBankAccountDetails, Err: err})
+	}
+	result.AddAction(mapping.TableBankAccountDetails, report.ActionDelete, bankAccountAffected, columns)
+
+	result.Changes = diffFields(tx.Mapper, mapping.TableSuppliers, before.supplier, supplierBean, supplierColumns)
+	if supplierDetailAffected > 0 {
+		result.Changes = append(result.Changes, diffFields(tx.Mapper, mapping.TableSupplierDetails, before.supplierDetail, supplierDetailBean, columns)...)
+	}
+	if bankAccountAffected > 0 {
+		result.Changes = append(result.Changes, diffFields(tx.Mapper, mapping.TableBankAccountDetails, before.bankAccount, bankAccountBean, columns)...)
+	}
+
+	return finishRow(tx, opts, result)
+}
@@ -0,0 +1,145 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+
+	config2 "github.com/lk153/import-gsheet/internal/config"
+	"github.com/lk153/import-gsheet/internal/mapping"
+	"github.com/lk153/import-gsheet/internal/models"
+	"github.com/lk153/import-gsheet/internal/report"
+	"github.com/lk153/import-gsheet/lib/db"
+	"github.com/lk153/import-gsheet/utils"
+)
+
+// Diff compares the rows of the run source with the stored suppliers and writes the reconciliation to
+// opts.ReportFile, or stdout when empty, in opts.ReportFormat or the format of its extension
+func Diff(opts Options) (err error) {
+	format, err := report.DiffFormat(opts.ReportFile, opts.ReportFormat)
+	if err != nil {
+		return err
+	}
+
+	database := db.Open(config2.GetCfg())
+	defer db.Close(database)
+	dbInstance := sqlx.NewDb(database, "mysql").Unsafe()
+
+	src, err := newSource(opts)
+	if err != nil {
+		return err
+	}
+
+	reconciliation, err := DiffRows(dbInstance, src, opts)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if opts.ReportFile != "" {
+		f, err := os.Create(opts.ReportFile)
+		if err != nil {
+			return fmt.Errorf("create reconciliation file: %w", err)
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+
+	if err = reconciliation.Write(w, format, opts.ReportFile == ""); err != nil {
+		return err
+	}
+
+	if opts.ReportFile != "" {
+		fmt.Println(utils.Info("Reconciliation written to ", opts.ReportFile))
+	}
+	return nil
+}
+
+// DiffRows compares the rows of src with the stored suppliers. The cells are parsed like Import does and blank cells,
+// which Import leaves untouched, are not compared.
+func DiffRows(dbInstance DB, src RowSource, opts Options) (*report.Reconciliation, error) {
+	cates, m, err := loadReferences(dbInstance, opts)
+	if err != nil {
+		return nil, err
+	}
+	values, opts, err := readSheet(src, m, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation := report.NewReconciliation(opts.SourceName())
+	for _, row := range values {
+		if row.isBlank() {
+			continue
+		}
+
+		diffRow(dbInstance, cates, row, reconciliation.NewRow(row.number))
+	}
+
+	reconciliation.Finish()
+	return reconciliation, nil
+}
+
+// diffRow fills in the status and the differing fields of the row
+func diffRow(dbInstance DB, cates *categories, row sheetRow, result *report.DiffRow) {
+	if isNewSupplier(row) {
+		result.Status = report.DiffNew
+		return
+	}
+
+	supplierID, err := parseSupplierID(row)
+	if err != nil {
+		result.Status, result.Error = report.DiffInvalid, err.Error()
+		return
+	}
+	result.SupplierID = supplierID
+
+	supplierBean, supplierDetailBean, bankAccountBean := &models.Supplier{}, &models.SupplierDetail{}, &models.BankAccountDetails{}
+	supplierColumns, supplierErr := row.apply(mapping.TableSuppliers, supplierBean)
+	supplierDetailColumns, supplierDetailErr := row.apply(mapping.TableSupplierDetails, supplierDetailBean)
+	bankAccountColumns, bankAccountErr := row.apply(mapping.TableBankAccountDetails, bankAccountBean)
+	categoryIDs, categoryErr := cates.resolve(row.get(colCategories))
+	if err = errors.Join(supplierErr, supplierDetailErr, bankAccountErr, categoryErr); err != nil {
+		result.Status, result.Error = report.DiffInvalid, err.Error()
+		return
+	}
+
+	state, err := loadCurrentState(dbInstance, supplierID)
+	if errors.Is(err, ErrSupplierNotFound) {
+		result.Status = report.DiffMissing
+		return
+	}
+	if err != nil {
+		result.Status, result.Error = report.DiffError, err.Error()
+		return
+	}
+
+	changes := diffFields(dbMapper, mapping.TableSuppliers, state.supplier, supplierBean, supplierColumns)
+	changes = append(changes, diffFields(dbMapper, mapping.TableSupplierDetails, state.supplierDetail, supplierDetailBean, supplierDetailColumns)...)
+	changes = append(changes, diffFields(dbMapper, mapping.TableBankAccountDetails, state.bankAccount, bankAccountBean, bankAccountColumns)...)
+
+	if categoryIDs != nil {
+		var current []uint
+		err = sqlx.Select(dbInstance, &current, `SELECT category_id FROM supplier_categories
+			WHERE supplier_id = ? AND deleted_at IS NULL
+			ORDER BY category_id;`, supplierID)
+		if err != nil {
+			result.Status, result.Error = report.DiffError, fmt.Sprintf("load supplier categories: %v", err)
+			return
+		}
+		changes = append(changes, categoryChanges(current, categoryIDs)...)
+	}
+
+	result.Status = report.DiffInSync
+	for _, change := range changes {
+		result.Status = report.DiffDiffers
+		result.Fields = append(result.Fields, report.FieldDiff{Table: change.Table, Field: change.Field, Sheet: change.After, Stored: change.Before})
+	}
+}
@@ -0,0 +1,95 @@
+package imports
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+
+	"github.com/lk153/import-gsheet/internal/models"
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+// currentState holds the stored records of a supplier before the row is applied
+type currentState struct {
+	supplier       *models.Supplier
+	supplierDetail *models.SupplierDetail
+	bankAccount    *models.BankAccountDetails
+}
+
+func loadCurrentState(q sqlx.Queryer, supplierID int64) (state *currentState, err error) {
+	state = &currentState{
+		supplier:       &models.Supplier{},
+		supplierDetail: &models.SupplierDetail{},
+		bankAccount:    &models.BankAccountDetails{},
+	}
+
+	err = sqlx.Get(q, state.supplier, `SELECT * FROM suppliers WHERE id = ? AND deleted_at IS NULL;`, supplierID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: id %d", ErrSupplierNotFound, supplierID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load supplier %d: %w", supplierID, err)
+	}
+
+	err = sqlx.Get(q, state.supplierDetail, `SELECT * FROM supplier_details WHERE supplier_id = ? AND deleted_at IS NULL;`, supplierID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("load supplier details %d: %w", supplierID, err)
+	}
+
+	err = sqlx.Get(q, state.bankAccount, `SELECT * FROM bank_account_details WHERE supplier_id = ? AND deleted_at IS NULL;`, supplierID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("load bank account details %d: %w", supplierID, err)
+	}
+
+	return state, nil
+}
+
+// diffFields compares the given db columns of the before and after beans and returns the ones that differ
+func diffFields(mapper *reflectx.Mapper, table string, before, after any, columns []string) (changes []report.Change) {
+	beforeValue := reflect.Indirect(reflect.ValueOf(before))
+	afterValue := reflect.Indirect(reflect.ValueOf(after))
+	for _, column := range columns {
+		b := formatValue(mapper.FieldByName(beforeValue, column))
+		a := formatValue(mapper.FieldByName(afterValue, column))
+		if a == b {
+			continue
+		}
+
+		changes = append(changes, report.Change{Table: table, Field: column, Before: b, After: a})
+	}
+
+	return
+}
+
+// formatValue renders a bean field the way the database stores it, NULL for invalid sql.Null* values
+func formatValue(field reflect.Value) string {
+	if !field.IsValid() {
+		return ""
+	}
+
+	value := field.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err.Error()
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		return v.Format(time.DateTime)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,61 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+// ErrSupplierNotFound is returned when a statement of the row matched no record of the supplier
+var ErrSupplierNotFound = errors.New("supplier not found")
+
+// ErrConflict is returned when the supplier was updated after the row was prepared and the run is not forced
+var ErrConflict = errors.New("supplier was updated after the sheet was prepared")
+
+// ErrNoWriteBack is returned for a row without supplier ID when the new ID cannot be written back to the source
+var ErrNoWriteBack = errors.New("the new supplier ID cannot be written back to the source and a rerun would create the supplier again, " +
+	"import the row from a Google sheet with a Supplier ID column or allow it with --insert-without-write-back")
+
+// ErrDeleteWithoutReport is returned by a run deleting suppliers without report, the only record of the delete reasons
+var ErrDeleteWithoutReport = errors.New("the rows delete suppliers: pass --report to record why, the tables have no column for the delete reason")
+
+// RowError is returned by BulkUpdate when a row is not imported.
+// Nothing of the row is written to the database when it is returned.
+type RowError struct {
+	// Stage is one of report.StageParse, report.StageValidation, report.StageConflict or report.StageDB
+	Stage string
+	// Table is the table whose statement failed, if any
+	Table string
+	Err   error
+}
+
+func (e *RowError) Error() string {
+	if e.Table != "" {
+		return fmt.Sprintf("%s %s: %v", e.Stage, e.Table, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Skipped reports whether the row was rejected before reaching the database
+func (e *RowError) Skipped() bool {
+	return e.Stage != report.StageDB
+}
+
+// rowStatus classifies the result of BulkUpdate
+func rowStatus(err error) string {
+	var rowErr *RowError
+	switch {
+	case err == nil:
+		return report.StatusOK
+	case errors.As(err, &rowErr) && rowErr.Skipped():
+		return report.StatusSkipped
+	default:
+		return report.StatusFailed
+	}
+}
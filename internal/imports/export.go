@@ -0,0 +1,329 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	config2 "github.com/lk153/import-gsheet/internal/config"
+	"github.com/lk153/import-gsheet/internal/mapping"
+	"github.com/lk153/import-gsheet/internal/models"
+	"github.com/lk153/import-gsheet/lib/db"
+	"github.com/lk153/import-gsheet/utils"
+)
+
+// exportBatchSize is the number of suppliers whose records are loaded per query
+const exportBatchSize = 500
+
+// ExportFilter selects the suppliers to export, every supplier when it is empty
+type ExportFilter struct {
+	IDs    []int64
+	Status string
+	// Category is the name of a category the suppliers are assigned to
+	Category string
+	// UpdatedSince selects the suppliers created or updated since then, their details and bank account included
+	UpdatedSince time.Time
+}
+
+// exportRecord holds the stored records of an exported supplier, the details and bank account are nil when it has none
+type exportRecord struct {
+	supplier       *models.Supplier
+	supplierDetail *models.SupplierDetail
+	bankAccount    *models.BankAccountDetails
+	categories     []string
+}
+
+// Export writes the suppliers selected by the filter to the Google sheet or the CSV file of opts, in the layout
+// Import reads: the mapping headers on the header row and a row per supplier from the first row of the range
+func Export(opts Options, filter ExportFilter) error {
+	database := db.Open(config2.GetCfg())
+	defer db.Close(database)
+	dbInstance := sqlx.NewDb(database, "mysql").Unsafe()
+
+	switch opts.Source {
+	case "", SourceGSheet:
+		if opts.SpreadsheetID == "" {
+			return errors.New("spreadsheet ID is empty")
+		}
+
+		dst, err := newGSheetSource(opts.SpreadsheetID)
+		if err != nil {
+			return err
+		}
+		return ExportRows(dbInstance, dst, opts, filter)
+	case SourceCSV:
+		if opts.File == "" {
+			return errors.New("CSV file is empty")
+		}
+
+		dst := &MemorySource{}
+		if err := ExportRows(dbInstance, dst, opts, filter); err != nil {
+			return err
+		}
+		return writeCSVFile(opts, dst.Rows)
+	default:
+		return fmt.Errorf("cannot export to %q, expected %s or %s", opts.Source, SourceGSheet, SourceCSV)
+	}
+}
+
+// ExportRows writes the suppliers selected by the filter to dst like Export
+func ExportRows(dbInstance DB, dst RowWriter, opts Options, filter ExportFilter) error {
+	m, err := mapping.Load(opts.MappingFile)
+	if err != nil {
+		return err
+	}
+
+	if err = loadLookups(dbInstance, m); err != nil {
+		return err
+	}
+
+	opts = opts.withDefaultRange(m)
+	rng, err := parseA1Range(opts.Range)
+	if err != nil {
+		return err
+	}
+
+	if opts.HeaderRow <= 0 || opts.HeaderRow >= rng.StartRow {
+		return fmt.Errorf("header row %d must be above the first data row %d", opts.HeaderRow, rng.StartRow)
+	}
+
+	// the rows are stamped with the time before they are read, for the conflict check of the import
+	asOf := time.Now().UTC().Truncate(time.Second)
+	records, err := loadExportRecords(dbInstance, filter)
+	if err != nil {
+		return err
+	}
+
+	// a narrower range would not be read back whole, dropping the last columns and their updates on the next import
+	lastCol := columnName(rng.StartCol + len(m.Columns) - 1)
+	if !rng.contains(rng.StartCol + len(m.Columns) - 1) {
+		return fmt.Errorf("the %d columns of the mapping end at column %s, beyond the range %s: export to %s%d:%s or leave --range empty",
+			len(m.Columns), lastCol, opts.Range, columnName(rng.StartCol), rng.StartRow, lastCol)
+	}
+
+	headers := make([]string, 0, len(m.Columns))
+	for _, col := range m.Columns {
+		headers = append(headers, col.Header)
+	}
+
+	var errs []error
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		row, err := exportRow(m, record, asOf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("supplier %d: %w", record.supplier.Id, err))
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if err = errors.Join(errs...); err != nil {
+		return err
+	}
+
+	headerRange := fmt.Sprintf("%s%d:%s%d", columnName(rng.StartCol), opts.HeaderRow, lastCol, opts.HeaderRow)
+	if err = dst.WriteRows(opts.sheetRange(headerRange), [][]string{headers}); err != nil {
+		return err
+	}
+
+	dataRange := fmt.Sprintf("%s%d:%s", columnName(rng.StartCol), rng.StartRow, lastCol)
+	if err = dst.WriteRows(opts.sheetRange(dataRange), rows); err != nil {
+		return err
+	}
+
+	fmt.Println(utils.Info("Exported ", len(rows), " suppliers to ", opts.SourceName()))
+	return nil
+}
+
+// exportRow renders the cells of the supplier in the order of the mapping columns, the columns read by the importer
+// itself are blank but for the supplier ID, the categories and the as-of time, and so are the ones of the records it
+// does not have
+func exportRow(m *mapping.Mapping, record exportRecord, asOf time.Time) ([]string, error) {
+	var errs []error
+	row := make([]string, 0, len(m.Columns))
+	for _, col := range m.Columns {
+		var cell string
+		var err error
+		switch col.Table {
+		case "":
+			switch col.Key {
+			case colSupplierID:
+				cell = strconv.FormatInt(record.supplier.Id, 10)
+			case colCategories:
+				cell = strings.Join(record.categories, ", ")
+			case colAsOf:
+				cell = col.FormatTime(asOf)
+			}
+		case mapping.TableSuppliers:
+			cell, err = col.Format(record.supplier)
+		case mapping.TableSupplierDetails:
+			if record.supplierDetail != nil {
+				cell, err = col.Format(record.supplierDetail)
+			}
+		case mapping.TableBankAccountDetails:
+			if record.bankAccount != nil {
+				cell, err = col.Format(record.bankAccount)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", col.Header, err))
+		}
+		row = append(row, cell)
+	}
+
+	return row, errors.Join(errs...)
+}
+
+// loadExportRecords loads the suppliers selected by the filter, ordered by ID, with their details,
+// bank account and category names
+func loadExportRecords(q sqlx.Queryer, filter ExportFilter) ([]exportRecord, error) {
+	query := `SELECT DISTINCT s.id FROM suppliers s
+		LEFT JOIN supplier_details d ON d.supplier_id = s.id AND d.deleted_at IS NULL
+		LEFT JOIN bank_account_details b ON b.supplier_id = s.id AND b.deleted_at IS NULL
+		WHERE s.deleted_at IS NULL`
+	var args []any
+	if len(filter.IDs) > 0 {
+		query += ` AND s.id IN (?)`
+		args = append(args, filter.IDs)
+	}
+	if filter.Status != "" {
+		query += ` AND s.status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.Category != "" {
+		query += ` AND EXISTS (SELECT 1 FROM supplier_categories sc
+			JOIN categories c ON c.category_id = sc.category_id AND c.deleted_at IS NULL
+			WHERE sc.supplier_id = s.id AND sc.deleted_at IS NULL AND c.name = ?)`
+		args = append(args, filter.Category)
+	}
+	if !filter.UpdatedSince.IsZero() {
+		query += ` AND (s.created_at >= ? OR s.updated_at >= ? OR d.updated_at >= ? OR b.updated_at >= ?)`
+		args = append(args, filter.UpdatedSince, filter.UpdatedSince, filter.UpdatedSince, filter.UpdatedSince)
+	}
+	query += ` ORDER BY s.id`
+
+	var ids []int64
+	if err := selectIn(q, &ids, query, args...); err != nil {
+		return nil, fmt.Errorf("loadExportRecords: %w", err)
+	}
+
+	records := make([]exportRecord, 0, len(ids))
+	for start := 0; start < len(ids); start += exportBatchSize {
+		end := start + exportBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		batch, err := loadExportBatch(q, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, batch...)
+	}
+
+	return records, nil
+}
+
+// loadExportBatch loads the records of the suppliers, the first details and bank account of each one
+func loadExportBatch(q sqlx.Queryer, ids []int64) ([]exportRecord, error) {
+	var suppliers []*models.Supplier
+	if err := selectIn(q, &suppliers, `SELECT * FROM suppliers WHERE id IN (?) ORDER BY id;`, ids); err != nil {
+		return nil, fmt.Errorf("load suppliers: %w", err)
+	}
+
+	var details []*models.SupplierDetail
+	err := selectIn(q, &details, `SELECT * FROM supplier_details
+		WHERE supplier_id IN (?) AND deleted_at IS NULL ORDER BY id;`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load supplier details: %w", err)
+	}
+
+	var bankAccounts []*models.BankAccountDetails
+	err = selectIn(q, &bankAccounts, `SELECT * FROM bank_account_details
+		WHERE supplier_id IN (?) AND deleted_at IS NULL ORDER BY id;`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load bank account details: %w", err)
+	}
+
+	var categoryNames []struct {
+		SupplierId int64  `db:"supplier_id"`
+		Name       string `db:"name"`
+	}
+	// only the leaf categories that are not deleted, the ones import resolves, like getMostChildCateMap
+	err = selectIn(q, &categoryNames, `SELECT sc.supplier_id, c.name FROM supplier_categories sc
+		JOIN categories c ON c.category_id = sc.category_id AND c.deleted_at IS NULL
+		WHERE sc.supplier_id IN (?) AND sc.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM categories c2 WHERE c2.parent_id = c.category_id AND c2.deleted_at IS NULL
+		)
+		ORDER BY sc.supplier_id, c.name;`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load supplier categories: %w", err)
+	}
+
+	records := make([]exportRecord, len(suppliers))
+	byID := make(map[int64]*exportRecord, len(suppliers))
+	for i, s := range suppliers {
+		records[i].supplier = s
+		byID[s.Id] = &records[i]
+	}
+
+	for i := len(details) - 1; i >= 0; i-- {
+		if r, ok := byID[details[i].SupplierId]; ok {
+			r.supplierDetail = details[i]
+		}
+	}
+	for i := len(bankAccounts) - 1; i >= 0; i-- {
+		if r, ok := byID[bankAccounts[i].SupplierId]; ok {
+			r.bankAccount = bankAccounts[i]
+		}
+	}
+	for _, c := range categoryNames {
+		if r, ok := byID[c.SupplierId]; ok {
+			r.categories = append(r.categories, strings.TrimSpace(c.Name))
+		}
+	}
+
+	return records, nil
+}
+
+// selectIn runs the query with its IN (?) expanded to the args
+func selectIn(q sqlx.Queryer, dest any, query string, args ...any) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+
+	return sqlx.Select(q, dest, query, args...)
+}
+
+// writeCSVFile writes the rows to the CSV file of opts, with its delimiter and encoding
+func writeCSVFile(opts Options, rows [][]string) error {
+	comma, err := csvDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = comma
+	if err = w.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV file %s: %w", opts.File, err)
+	}
+
+	b, err := encodeBytes(buf.Bytes(), opts.Encoding)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(opts.File, b, 0o644); err != nil {
+		return fmt.Errorf("write CSV file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package imports
+
+import (
+	"strings"
+)
+
+// fileSource is the RowSource of the rows read from a file, the import result is not written back to it
+type fileSource struct {
+	records [][]string
+}
+
+func (s fileSource) ReadRows(readRange string) ([][]string, error) {
+	return sliceRange(s.records, readRange)
+}
+
+// sliceRange returns the cells of the A1 range of the records, the sheet name is ignored
+func sliceRange(records [][]string, readRange string) ([][]string, error) {
+	if i := strings.LastIndex(readRange, "!"); i >= 0 {
+		readRange = readRange[i+1:]
+	}
+
+	rng, err := parseA1Range(readRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]string
+	for idx := rng.StartRow - 1; idx < len(records) && (rng.EndRow == 0 || idx < rng.EndRow); idx++ {
+		record := records[idx]
+		if rng.StartCol >= len(record) {
+			values = append(values, []string{})
+			continue
+		}
+
+		end := rng.EndCol + 1
+		if rng.EndCol < 0 || end > len(record) {
+			end = len(record)
+		}
+		values = append(values, record[rng.StartCol:end])
+	}
+
+	return values, nil
+}
@@ -0,0 +1,155 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+)
+
+/*Keys of the mapping columns read by the importer itself*/
+const (
+	colSupplierID   = "supplier_id"
+	colCategories   = "categories"
+	colAction       = "action"
+	colDeleteReason = "delete_reason"
+	colAsOf         = "as_of"
+)
+
+// header maps the column keys to the 0-based index of their sheet column, A => 0
+type header map[string]int
+
+// parseHeader locates the mapping columns in the header row.
+// It fails when a required column is missing or when a column matches more than one header cell.
+func parseHeader(cells []string, m *mapping.Mapping) (header, error) {
+	names := map[string]string{}
+	for _, col := range m.Columns {
+		for _, name := range append([]string{col.Key, col.Header}, col.Aliases...) {
+			names[mapping.NormalizeName(name)] = col.Key
+		}
+	}
+
+	h := header{}
+	for idx, cell := range cells {
+		key, ok := names[mapping.NormalizeName(cell)]
+		if !ok {
+			continue
+		}
+
+		if prev, ok := h[key]; ok {
+			return nil, fmt.Errorf("column %s matches both header %q and %q", key, cells[prev], cell)
+		}
+		h[key] = idx
+	}
+
+	missing := []string{}
+	for _, col := range m.Columns {
+		if _, ok := h[col.Key]; col.Required && !ok {
+			missing = append(missing, fmt.Sprintf("%q", col.Header))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("required header(s) missing: %s", strings.Join(missing, ", "))
+	}
+
+	return h, nil
+}
+
+// checkRange fails when a column of the header is outside the columns of the range: its cells would not be read,
+// nor its import result written
+func (h header) checkRange(m *mapping.Mapping, rng a1Range, readRange string) error {
+	outside := []string{}
+	for _, col := range m.Columns {
+		if idx, ok := h[col.Key]; ok && !rng.contains(idx) {
+			outside = append(outside, fmt.Sprintf("%q (column %s)", col.Header, columnName(idx)))
+		}
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("header(s) outside the range %s: %s, widen --range or leave it empty", readRange, strings.Join(outside, ", "))
+	}
+
+	return nil
+}
+
+// sheetRow is a data row whose cells are looked up by column key
+type sheetRow struct {
+	mapping *mapping.Mapping
+	header  header
+	cells   []string
+	// number is the row number in the sheet and firstCol the index of the first column read
+	number   int
+	firstCol int
+}
+
+// isBlank reports whether the row has no data, the import result columns aside
+func (r sheetRow) isBlank() bool {
+	for key := range r.header {
+		switch key {
+		case colImportStatus, colImportTimestamp, colImportError:
+			continue
+		}
+		if strings.TrimSpace(r.get(key)) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cellRange returns the A1 range of the row cell in the column, if the sheet has the column
+func (r sheetRow) cellRange(opts Options, key string) (string, bool) {
+	idx, ok := r.header[key]
+	if !ok {
+		return "", false
+	}
+
+	return opts.sheetRange(fmt.Sprintf("%s%d", columnName(idx), r.number)), true
+}
+
+// get returns the cell of the column, or an empty string when the sheet has no such column
+// or the row is shorter than the header (Google Sheets drops trailing empty cells)
+func (r sheetRow) get(key string) string {
+	idx, ok := r.header[key]
+	if !ok || idx-r.firstCol >= len(r.cells) {
+		return ""
+	}
+
+	return r.cells[idx-r.firstCol]
+}
+
+// roundings describes the cells of the mapped columns rounded to be stored, see mapping.Column.Rounding
+func (r sheetRow) roundings() (warnings []string) {
+	for _, col := range r.mapping.Columns {
+		if _, ok := r.header[col.Key]; !ok || col.Table == "" {
+			continue
+		}
+
+		if warning := col.Rounding(r.get(col.Key)); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	return warnings
+}
+
+// apply writes the cells of the columns mapped to the table into the bean and returns the db columns it set
+func (r sheetRow) apply(table string, bean any) (columns []string, err error) {
+	var errs []error
+	for _, col := range r.mapping.TableColumns(table) {
+		if _, ok := r.header[col.Key]; !ok {
+			continue
+		}
+
+		set, err := col.Apply(bean, r.get(col.Key))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if set {
+			columns = append(columns, col.Field)
+		}
+	}
+
+	return columns, errors.Join(errs...)
+}
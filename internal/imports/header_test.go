@@ -0,0 +1,104 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+)
+
+func TestParseHeader(t *testing.T) {
+	m, err := mapping.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cells   []string
+		want    header
+		wantErr string
+	}{
+		{
+			name:  "headers",
+			cells: []string{"Supplier ID", "", "Company Name", "Notes"},
+			want:  header{colSupplierID: 0, "company_name": 2},
+		},
+		{
+			name:  "aliases, case and spacing",
+			cells: []string{" supplier id ", "Tier", "GMV in RMB", "margin"},
+			want:  header{colSupplierID: 0, "supplier_tier": 1, "gmv_in_rmb": 2, "margin_in_percentage": 3},
+		},
+		{
+			name:  "keys",
+			cells: []string{"supplier_id", "company_name"},
+			want:  header{colSupplierID: 0, "company_name": 1},
+		},
+		{
+			name:    "missing required header",
+			cells:   []string{"Company Name", "Country"},
+			wantErr: `required header(s) missing: "Supplier ID"`,
+		},
+		{
+			name:    "header matched twice",
+			cells:   []string{"Supplier ID", "Company Name", "ID"},
+			wantErr: `column supplier_id matches both header "Supplier ID" and "ID"`,
+		},
+		{
+			name:    "alias matched twice",
+			cells:   []string{"Supplier ID", "Margin (%)", "Margin"},
+			wantErr: `column margin_in_percentage matches both header "Margin (%)" and "Margin"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeader(tt.cells, m)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseHeader() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseHeader() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderCheckRange(t *testing.T) {
+	m, err := mapping.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := header{colSupplierID: 1, "company_name": 2, colImportError: 54}
+	tests := []struct {
+		rng     string
+		wantErr string
+	}{
+		{rng: "B3:BC"},
+		{rng: "A3:3"},
+		{rng: "A3:BB", wantErr: `header(s) outside the range A3:BB: "Import Error" (column BC)`},
+		{rng: "C3:BC", wantErr: `"Supplier ID" (column B)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			rng, err := parseA1Range(tt.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = h.checkRange(m, rng, tt.rng)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkRange() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
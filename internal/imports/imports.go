@@ -4,508 +4,647 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	"github.com/lk153/gsheet-go/lib"
+	"github.com/jmoiron/sqlx/reflectx"
 
 	config2 "github.com/lk153/import-gsheet/internal/config"
+	"github.com/lk153/import-gsheet/internal/mapping"
 	"github.com/lk153/import-gsheet/internal/models"
+	"github.com/lk153/import-gsheet/internal/report"
 	"github.com/lk153/import-gsheet/lib/db"
 	"github.com/lk153/import-gsheet/utils"
 )
 
-func Import() {
-	database := db.Open(config2.GetCfg())
-	sqlxDB := sqlx.NewDb(database, "mysql")
-	dbInstance := sqlxDB.Unsafe()
+/*Sources a run can read its rows from*/
+const (
+	SourceGSheet = "gsheet"
+	SourceCSV    = "csv"
+	SourceXLSX   = "xlsx"
+)
 
-	/*Get Categories map for later updates*/
-	// cateMap := getMostChildCateMap(dbInstance)
-	// fmt.Println("cateMap", cateMap)
-	// os.Exit(1)
+// Options describes which spreadsheet and range a run reads its rows from
+type Options struct {
+	// Source is SourceGSheet (the default), SourceCSV or SourceXLSX
+	Source        string
+	SpreadsheetID string
+	Sheet         string
+	// Range is the A1 range of the data rows, by default the rows below HeaderRow as wide as the header row, or as
+	// the mapping for an export
+	Range string
+	// File is the CSV or XLSX file of the file sources, Sheet selects the XLSX sheet (the first one when empty).
+	// Delimiter and Encoding describe the CSV file, comma separated UTF-8 by default.
+	File      string
+	Delimiter string
+	Encoding  string
+	// HeaderRow is the sheet row number holding the column headers, it must be above Range
+	HeaderRow int
+	// MappingFile is a yaml or json file mapping the sheet columns to the model fields, the default layout when empty
+	MappingFile string
+	// ReportFile is where the run report is written, in ReportFormat or the format of its extension
+	ReportFile   string
+	ReportFormat string
+	// DryRun executes the statements of every row and prints what they change, then rolls back
+	DryRun bool
+	// Operator is recorded as created_by of the suppliers the run creates and deleted_by of the ones it deletes
+	Operator string
+	// AsOf is the time, in UTC, the rows without as-of cell were prepared at. The rows of the suppliers updated since
+	// are refused unless Force is set; the zero time disables the check.
+	AsOf  time.Time
+	Force bool
+	// InsertWithoutWriteBack creates the suppliers of the rows without supplier ID even when their new ID cannot be
+	// written back to the source, a CSV or XLSX file; a rerun of the same rows then creates them again
+	InsertWithoutWriteBack bool
+}
 
-	srv, err := lib.NewGsheetServiceV2()
-	if err != nil {
-		fmt.Println("Cannot connect Gsheet!")
-		return
-	}
-
-	// spreadsheetID := "1JhddKKmez9thv8lKmybM09o_MiEbAnPlxitIAqeyK04"
-	spreadsheetID := "1c-onQeYHmvc-EPkrJDU-WyAydbCAA1ng6hXCgdYiqqg"
-	readRange := "'To Update on DB'!A3:AR3"
-	values := srv.ReadSheet(spreadsheetID, readRange)
-	for idx, row := range values {
-		fmt.Println(utils.Info("=========================================================== " + strconv.Itoa(idx) + " ==========================================================="))
-		fmt.Println("Data: ", strings.Join(row, " | "))
-		fmt.Println()
-		fmt.Println("*----------------------------------------------------------DB----------------------------------------------------------*")
-		if err = BulkUpdate(dbInstance, row); err != nil {
-			fmt.Println("ERROR: ", err.Error())
-		}
-		fmt.Println("*----------------------------------------------------------------------------------------------------------------------*")
-		fmt.Println()
-		fmt.Println()
+// DB is the database a run reads the references from and writes the rows to in transactions: a *sqlx.DB of the MySQL
+// driver, or of any driver running the same statements
+type DB interface {
+	sqlx.Queryer
+	Beginx() (*sqlx.Tx, error)
+}
+
+// dbMapper maps the model fields to the db columns like the mapper of a *sqlx.DB
+var dbMapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// ReadRange returns the A1 notation of the range to read, e.g. 'To Update on DB'!A3:BA
+func (o Options) ReadRange() string {
+	return o.sheetRange(o.Range)
+}
+
+// SourceName describes where the rows are read from, for the run report
+func (o Options) SourceName() string {
+	switch o.Source {
+	case SourceCSV:
+		return o.File + "!" + o.Range
+	case SourceXLSX:
+		return o.File + "!" + o.ReadRange()
 	}
+
+	return o.ReadRange()
 }
 
-func BulkUpdate(dbInstance *sqlx.DB, row []string) (err error) {
-	supplierID, err := strconv.ParseInt(row[0], 10, 64)
-	if err != nil {
-		fmt.Println("BulkUpdate:ERROR: ", err.Error(), row[0])
-		return errors.New(fmt.Sprintf("BulkUpdate:ERROR: %v - %s", err.Error(), row[0]))
+// withDefaultRange returns the options with the range, when empty, set to the rows below the header row from column A
+// to the last column of the mapping, the columns Export writes
+func (o Options) withDefaultRange(m *mapping.Mapping) Options {
+	if o.Range == "" {
+		o.Range = fmt.Sprintf("A%d:%s", o.HeaderRow+1, columnName(len(m.Columns)-1))
 	}
 
-	if supplierID == 0 {
-		fmt.Println("BulkUpdate:ERROR: supplierID is empty")
-		return errors.New(fmt.Sprintf("BulkUpdate:ERROR: supplierID is empty"))
+	return o
+}
+
+// sheetRange prefixes the range with the quoted sheet name
+func (o Options) sheetRange(rng string) string {
+	if o.Sheet == "" {
+		return rng
 	}
 
-	/*Init Supplier and related models*/
-	supplierBean := &models.Supplier{Id: supplierID}
-	supplierDetailBean := &models.SupplierDetail{SupplierId: supplierID}
-	bankAccountBean := &models.BankAccountDetails{SupplierId: supplierID}
+	return fmt.Sprintf("'%s'!%s", strings.ReplaceAll(o.Sheet, "'", "''"), rng)
+}
 
-	/*Prepare Supplier updation query*/
-	updateSupplierQuery := prepareSupplierUpdateSQL(supplierBean, row)
-	updateSupplierDetailQuery := prepareSupplierDetailUpdateSQL(supplierDetailBean, row)
+// Import reads the rows of the run source and imports them to the database
+func Import(opts Options) error {
+	database := db.Open(config2.GetCfg())
+	defer db.Close(database)
+	sqlxDB := sqlx.NewDb(database, "mysql")
+	dbInstance := sqlxDB.Unsafe()
 
-	/*Execute Supplier updation query on DB*/
-	tx := dbInstance.MustBegin()
+	src, err := newSource(opts)
+	if err != nil {
+		return err
+	}
 
-	if err = execSupplierUpdate(tx, updateSupplierQuery, supplierBean); err != nil {
-		if err = tx.Rollback(); err != nil {
-			fmt.Println(utils.Fatal("execSupplierUpdate: Rollback Failed: ", err.Error()))
-		}
+	return ImportRows(dbInstance, src, opts)
+}
+
+// ImportRows imports the rows of src to the database, and writes the result of each row back to src when it is a
+// CellWriter. It returns an error when a row failed or was skipped, once every row is processed and the report saved.
+func ImportRows(dbInstance DB, src RowSource, opts Options) error {
+	/*Get Categories map and lookup values for later updates*/
+	cates, m, err := loadReferences(dbInstance, opts)
+	if err != nil {
+		return err
+	}
+	values, opts, err := readSheet(src, m, opts)
+	if err != nil {
+		return err
 	}
 
-	if err = execSupplierDetailUpdate(tx, updateSupplierDetailQuery, supplierDetailBean); err != nil {
-		if err = tx.Rollback(); err != nil {
-			fmt.Println(utils.Fatal("execSupplierDetailUpdate: Rollback Failed: ", err.Error()))
+	if opts.ReportFile != "" {
+		if _, err = report.FileFormat(opts.ReportFile, opts.ReportFormat); err != nil {
+			return err
 		}
+	} else if !opts.DryRun && hasDeleteRows(values) {
+		return ErrDeleteWithoutReport
 	}
 
-	if isBankInformationExist(dbInstance, supplierID) {
-		updateBankAccountQuery := prepareBankAccountDetailUpdateSQL(bankAccountBean, row)
-		if err = execBankAccountUpdate(tx, updateBankAccountQuery, bankAccountBean); err != nil {
-			if err = tx.Rollback(); err != nil {
-				fmt.Println(utils.Fatal("execBankAccountUpdate: Rollback Failed: ", err.Error()))
-			}
+	runReport := report.New(opts.SourceName(), opts.DryRun)
+	for _, row := range values {
+		if row.isBlank() {
+			continue
 		}
-	} else {
-		insertBankAccountQuery := prepareBankAccountDetailInsertSQL(bankAccountBean, row)
-		if err = execBankAccountInsert(tx, insertBankAccountQuery, bankAccountBean); err != nil {
-			if err = tx.Rollback(); err != nil {
-				fmt.Println(utils.Fatal("execBankAccountInsert: Rollback Failed: ", err.Error()))
+
+		result := runReport.NewRow(row.number)
+		err = importRow(dbInstance, src, cates, row, opts, result)
+		result.Status = rowStatus(err)
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			result.AddError(rowErr.Stage, rowErr.Table, rowErr.Err)
+		}
+		if w, ok := src.(CellWriter); ok && !opts.DryRun {
+			if err = writeResult(w, opts, row, result.SupplierID, err); err != nil {
+				if isNewSupplier(row) && result.SupplierID != 0 {
+					err = fmt.Errorf("supplier %d is created but its ID is not in the sheet, fill it in or the next run creates the supplier again: %w", result.SupplierID, err)
+				}
+				result.AddError(report.StageWriteBack, "", err)
 			}
 		}
+		result.Print(os.Stdout)
 	}
 
-	if err = tx.Commit(); err != nil {
-		fmt.Println(utils.Fatal("Cannot commit DB transaction: ", err.Error()))
+	runReport.Finish()
+	runReport.PrintSummary(os.Stdout)
+	if opts.ReportFile != "" {
+		if err = runReport.Save(opts.ReportFile, opts.ReportFormat); err != nil {
+			return err
+		}
+		fmt.Println(utils.Info("Report written to ", opts.ReportFile))
 	}
 
-	// if !checkSupplierCategoryUpdated(dbInstance, supplierID, row[28]) {
-	// 	fmt.Println(utils.Fatal("checkSupplierCategoryUpdated: NOT Existed:", supplierID))
-	// }
+	if s := runReport.Summary; s.Failed > 0 || s.Skipped > 0 {
+		return fmt.Errorf("%d of %d rows were not imported: %d failed, %d skipped", s.Failed+s.Skipped, s.Rows, s.Failed, s.Skipped)
+	}
+	if s := runReport.Summary; s.WriteBackFailed > 0 {
+		return fmt.Errorf("the result of %d of %d rows could not be written back to the sheet", s.WriteBackFailed, s.Rows)
+	}
 
-	return
+	return nil
 }
 
-func execSupplierUpdate(tx *sqlx.Tx, updateSupplierQuery string, supplierBean *models.Supplier) (err error) {
-	var result sql.Result
-	if result, err = tx.NamedExec(updateSupplierQuery, supplierBean); err != nil {
-		fmt.Println(utils.Fatal("execSupplierUpdate: Error: ", err.Error()))
-		return
+// importRow deletes, creates or updates the supplier of the row depending on its action and supplier ID
+func importRow(dbInstance DB, src RowSource, cates *categories, row sheetRow, opts Options, result *report.Row) error {
+	action, err := parseAction(row)
+	if err != nil {
+		return &RowError{Stage: report.StageParse, Err: err}
 	}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		fmt.Println(utils.Fatal("execSupplierUpdate: RowsAffected: ", err.Error()))
-		return
+	if action == actionDelete {
+		return BulkDelete(dbInstance, row, opts, result)
 	}
 
-	fmt.Println(utils.Debug("execSupplierUpdate: Affected: ", affected))
-	return
+	result.Warnings = row.roundings()
+	switch {
+	case isNewSupplier(row):
+		if err = checkWriteBack(src, row, opts); err != nil {
+			return &RowError{Stage: report.StageValidation, Err: err}
+		}
+		return BulkInsert(dbInstance, cates, row, opts, result)
+	default:
+		return BulkUpdate(dbInstance, cates, row, opts, result)
+	}
 }
 
-func execSupplierDetailUpdate(tx *sqlx.Tx, updateSupplierDetailQuery string, supplierDetailBean *models.SupplierDetail) (err error) {
-	var result sql.Result
-	if result, err = tx.NamedExec(updateSupplierDetailQuery, supplierDetailBean); err != nil {
-		fmt.Println(utils.Fatal("execSupplierDetailUpdate: Error: ", err.Error()))
-		return
+// loadReferences loads the categories and the mapping with the values of its lookup columns
+func loadReferences(q sqlx.Queryer, opts Options) (*categories, *mapping.Mapping, error) {
+	cates, err := loadCategories(q)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	affected, err := result.RowsAffected()
+	m, err := mapping.Load(opts.MappingFile)
 	if err != nil {
-		fmt.Println(utils.Fatal("execSupplierDetailUpdate: RowsAffected: ", err.Error()))
-		return
+		return nil, nil, err
 	}
 
-	fmt.Println(utils.Debug("execSupplierDetailUpdate: Affected: ", affected))
-	return
+	if err = loadLookups(q, m); err != nil {
+		return nil, nil, err
+	}
+
+	return cates, m, nil
 }
 
-func execBankAccountUpdate(tx *sqlx.Tx, updateBankAccountQuery string, bankAccountBean *models.BankAccountDetails) (err error) {
-	var result sql.Result
-	if result, err = tx.NamedExec(updateBankAccountQuery, bankAccountBean); err != nil {
-		fmt.Println(utils.Fatal("execBankAccountUpdate: Error: ", err.Error()))
-		return
-	}
+// Validate reads the rows, runs the SQL builders and validates the models on them.
+// The database is only read, for the categories and the lookup values.
+func Validate(opts Options) error {
+	database := db.Open(config2.GetCfg())
+	defer db.Close(database)
+	dbInstance := sqlx.NewDb(database, "mysql").Unsafe()
 
-	affected, err := result.RowsAffected()
+	src, err := newSource(opts)
 	if err != nil {
-		fmt.Println(utils.Fatal("execBankAccountUpdate: RowsAffected: ", err.Error()))
-		return
+		return err
 	}
 
-	fmt.Println(utils.Debug("execBankAccountUpdate: Affected: ", affected))
-	return
+	return ValidateRows(dbInstance, src, opts)
 }
 
-func execBankAccountInsert(tx *sqlx.Tx, insertBankAccountQuery string, bankAccountBean *models.BankAccountDetails) (err error) {
-	var result sql.Result
-	if result, err = tx.NamedExec(insertBankAccountQuery, bankAccountBean); err != nil {
-		fmt.Println(utils.Fatal("execBankAccountInsert: Error: ", err.Error()))
-		return
+// ValidateRows validates the rows of src like Validate
+func ValidateRows(dbInstance DB, src RowSource, opts Options) error {
+	cates, m, err := loadReferences(dbInstance, opts)
+	if err != nil {
+		return err
 	}
-
-	lastInsertId, err := result.LastInsertId()
+	values, opts, err := readSheet(src, m, opts)
 	if err != nil {
-		fmt.Println(utils.Fatal("execBankAccountInsert: LastInsertId: ", err.Error()))
-		return
+		return err
 	}
 
-	fmt.Println(utils.Debug("execBankAccountInsert: Inserted ID: ", lastInsertId))
-	return
-}
+	checked, invalid := 0, 0
+	for _, row := range values {
+		if row.isBlank() {
+			continue
+		}
 
-func prepareSupplierUpdateSQL(s *models.Supplier, row []string) (updateSupplierQuery string) {
-	updateSupplierQuery = `UPDATE suppliers SET %s WHERE id = :id`
-	setFields := []string{}
+		checked++
+		note, err := validateRow(src, cates, row, opts)
+		if err != nil {
+			invalid++
+			fmt.Println(utils.Fatal("Row ", row.number, ": ", err.Error()))
+			continue
+		}
 
-	if len(strings.TrimSpace(row[1])) != 0 {
-		s.Entity = strings.TrimSpace(row[1])
-		setFields = append(setFields, `entity = :entity`)
-	}
-	if len(strings.TrimSpace(row[2])) != 0 {
-		s.CompanyName = strings.TrimSpace(row[2])
-		setFields = append(setFields, `company_name = :company_name`)
+		fmt.Println(utils.Info("Row ", row.number, ": OK", note))
+		for _, warning := range row.roundings() {
+			fmt.Println(utils.Warn("  warning: ", warning))
+		}
 	}
-	if len(strings.TrimSpace(row[3])) != 0 {
-		s.AlternateCompanyName = sql.NullString{String: strings.TrimSpace(row[3]), Valid: true}
-		setFields = append(setFields, `alternate_company_name = :alternate_company_name`)
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d rows are invalid", invalid, checked)
 	}
-	if len(strings.TrimSpace(row[8])) != 0 {
-		s.City = sql.NullString{String: strings.TrimSpace(row[8]), Valid: true}
-		setFields = append(setFields, `city = :city`)
+
+	return nil
+}
+
+// validateRow runs the checks of the row action and returns a note on what the row would do
+func validateRow(src RowSource, cates *categories, row sheetRow, opts Options) (note string, err error) {
+	action, err := parseAction(row)
+	if err != nil {
+		return "", err
 	}
-	if len(strings.TrimSpace(row[9])) != 0 {
-		s.LocationRegion = sql.NullString{String: strings.TrimSpace(row[9]), Valid: true}
-		setFields = append(setFields, `location_region = :location_region`)
+
+	if action != actionDelete {
+		if _, err = cates.resolve(row.get(colCategories)); err != nil {
+			return "", err
+		}
 	}
-	if len(strings.TrimSpace(row[10])) != 0 {
-		s.LegalPerson = sql.NullString{String: strings.TrimSpace(row[10]), Valid: true}
-		setFields = append(setFields, `legal_person = :legal_person`)
+
+	if _, err = rowAsOf(row, time.Time{}); err != nil {
+		return "", err
 	}
-	if len(strings.TrimSpace(row[11])) != 0 {
-		s.LegalPersonId = sql.NullString{String: strings.TrimSpace(row[11]), Valid: true}
-		setFields = append(setFields, `legal_person_id = :legal_person_id`)
+
+	switch {
+	case action == actionDelete:
+		return " (delete)", validateDelete(row)
+	case isNewSupplier(row):
+		if err = checkWriteBack(src, row, opts); err != nil {
+			return "", err
+		}
+		return " (new supplier)", validateInsert(row)
+	default:
+		return "", validateUpdate(row)
 	}
-	if len(strings.TrimSpace(row[13])) != 0 {
-		s.NumberOfEmployeesRangeID = sql.NullInt64{Int64: getNumberEmployeeRangeID(row[13]), Valid: true}
-		setFields = append(setFields, `number_of_employees_range_id = :number_of_employees_range_id`)
+}
+
+// validateUpdate runs the SQL builders and validates the models of an update row
+func validateUpdate(row sheetRow) error {
+	if _, err := parseSupplierID(row); err != nil {
+		return err
 	}
-	if len(strings.TrimSpace(row[14])) != 0 {
-		s.PassedVetting = sql.NullString{String: strings.TrimSpace(row[14]), Valid: true}
-		setFields = append(setFields, `passed_vetting = :passed_vetting`)
+
+	supplierBean, supplierDetailBean, bankAccountBean := &models.Supplier{}, &models.SupplierDetail{}, &models.BankAccountDetails{}
+	_, supplierColumns, supplierErr := prepareSupplierUpdateSQL(supplierBean, row)
+	_, supplierDetailColumns, supplierDetailErr := prepareSupplierDetailUpdateSQL(supplierDetailBean, row)
+	_, bankAccountColumns, bankAccountErr := prepareBankAccountDetailUpdateSQL(bankAccountBean, row)
+	if err := errors.Join(supplierErr, supplierDetailErr, bankAccountErr); err != nil {
+		return err
 	}
-	if len(strings.TrimSpace(row[15])) != 0 {
-		s.VettingInfoUrl = sql.NullString{String: strings.TrimSpace(row[15]), Valid: true}
-		setFields = append(setFields, `vetting_info_url = :vetting_info_url`)
+
+	return errors.Join(
+		validateBean(mapping.TableSuppliers, supplierBean, supplierColumns, false),
+		validateBean(mapping.TableSupplierDetails, supplierDetailBean, supplierDetailColumns, false),
+		validateBean(mapping.TableBankAccountDetails, bankAccountBean, bankAccountColumns, false),
+	)
+}
+
+// readSheet reads the header row and the data rows of the sheet laid out as described by the mapping. The header row
+// is read whole, and the range defaults to the rows below it as wide as it; the options are returned with that range.
+func readSheet(src RowSource, m *mapping.Mapping, opts Options) (rows []sheetRow, _ Options, err error) {
+	if opts.HeaderRow <= 0 {
+		return nil, opts, fmt.Errorf("invalid header row %d", opts.HeaderRow)
 	}
-	if len(strings.TrimSpace(row[16])) != 0 {
-		s.ContactPerson = strings.TrimSpace(row[16])
-		setFields = append(setFields, `contact_person = :contact_person`)
+
+	if _, ok := m.Column(colSupplierID); !ok {
+		return nil, opts, fmt.Errorf("mapping has no %s column", colSupplierID)
 	}
-	if len(strings.TrimSpace(row[17])) != 0 {
-		s.ContactNumber = strings.TrimSpace(row[17])
-		setFields = append(setFields, `contact_number = :contact_number`)
+
+	headerValues, err := src.ReadRows(opts.sheetRange(fmt.Sprintf("A%d:%d", opts.HeaderRow, opts.HeaderRow)))
+	if err != nil {
+		return nil, opts, err
 	}
-	if len(strings.TrimSpace(row[18])) != 0 {
-		s.SocialNetworkId = sql.NullString{String: strings.TrimSpace(row[18]), Valid: true}
-		setFields = append(setFields, `social_network_id = :social_network_id`)
+	if len(headerValues) == 0 || len(headerValues[0]) == 0 {
+		return nil, opts, fmt.Errorf("header row %d is empty", opts.HeaderRow)
 	}
 
-	updateSupplierQuery = fmt.Sprintf(updateSupplierQuery, strings.Join(setFields, ", "))
-	return
-}
+	h, err := parseHeader(headerValues[0], m)
+	if err != nil {
+		return nil, opts, err
+	}
 
-func prepareSupplierDetailUpdateSQL(sd *models.SupplierDetail, row []string) (updateSupplierDetailQuery string) {
-	updateSupplierDetailQuery = `UPDATE supplier_details SET %s WHERE supplier_id = :supplier_id`
-	setFields := []string{}
-	if len(strings.TrimSpace(row[4])) != 0 {
-		sd.BusinessRegistrationNumber = sql.NullString{String: strings.TrimSpace(row[4]), Valid: true}
-		setFields = append(setFields, `business_registration_number = :business_registration_number`)
-	}
-	if len(strings.TrimSpace(row[5])) != 0 {
-		sd.RegisteredBusinessAddress = sql.NullString{String: strings.TrimSpace(row[5]), Valid: true}
-		setFields = append(setFields, `registered_business_address = :registered_business_address`)
-	}
-	if len(strings.TrimSpace(row[6])) != 0 {
-		sd.SupplierAddress = sql.NullString{String: strings.TrimSpace(row[6]), Valid: true}
-		setFields = append(setFields, `supplier_address = :supplier_address`)
-	}
-	if len(strings.TrimSpace(row[7])) != 0 {
-		t, err := time.Parse("2006-01-02", strings.TrimSpace(row[7]))
-		if err == nil {
-			sd.DateOfEstablishment = sql.NullTime{Time: t, Valid: true}
-			setFields = append(setFields, `date_of_establishment = :date_of_establishment`)
-		}
+	if opts.Range == "" {
+		opts.Range = fmt.Sprintf("A%d:%s", opts.HeaderRow+1, columnName(len(headerValues[0])-1))
 	}
-	if len(strings.TrimSpace(row[12])) != 0 {
-		i, err := strconv.ParseInt(row[12], 10, 64)
-		if err == nil {
-			sd.PaidUpCapitalRMB = sql.NullInt64{Int64: i, Valid: true}
-			setFields = append(setFields, `paid_up_capital_in_rmb = :paid_up_capital_in_rmb`)
-		}
+	rng, err := parseA1Range(opts.Range)
+	if err != nil {
+		return nil, opts, err
 	}
-	if len(strings.TrimSpace(row[19])) != 0 {
-		sd.EmailAddress = sql.NullString{String: strings.TrimSpace(row[19]), Valid: true}
-		setFields = append(setFields, `email_address = :email_address`)
+
+	if opts.HeaderRow >= rng.StartRow {
+		return nil, opts, fmt.Errorf("header row %d must be above the first data row %d", opts.HeaderRow, rng.StartRow)
 	}
-	if len(strings.TrimSpace(row[20])) != 0 {
-		sd.SupplierWebsiteURL = sql.NullString{String: strings.TrimSpace(row[20]), Valid: true}
-		setFields = append(setFields, `supplier_website_url = :supplier_website_url`)
+
+	if err = h.checkRange(m, rng, opts.Range); err != nil {
+		return nil, opts, err
 	}
-	if len(strings.TrimSpace(row[21])) != 0 {
-		sd.SupplierType = sql.NullString{String: strings.TrimSpace(row[21]), Valid: true}
-		setFields = append(setFields, `supplier_type = :supplier_type`)
+
+	dataValues, err := src.ReadRows(opts.ReadRange())
+	if err != nil {
+		return nil, opts, err
 	}
-	if len(strings.TrimSpace(row[22])) != 0 {
-		i, err := strconv.ParseInt(row[22], 10, 16)
-		if err == nil {
-			sd.BrandedGoods = int16(i)
-			setFields = append(setFields, `branded_goods = :branded_goods`)
-		}
+
+	for idx, cells := range dataValues {
+		rows = append(rows, sheetRow{mapping: m, header: h, cells: cells, number: rng.StartRow + idx, firstCol: rng.StartCol})
 	}
-	if len(strings.TrimSpace(row[23])) != 0 {
-		sd.BrandCheckID = sql.NullString{String: strings.TrimSpace(row[23]), Valid: true}
-		setFields = append(setFields, `brand_check_id = :brand_check_id`)
+
+	return rows, opts, nil
+}
+
+func parseSupplierID(row sheetRow) (supplierID int64, err error) {
+	value := strings.TrimSpace(row.get(colSupplierID))
+	if value == "" {
+		return 0, errors.New("supplierID is empty")
 	}
-	if len(strings.TrimSpace(row[25])) != 0 {
-		sd.OriginSource = strings.TrimSpace(row[25])
-		setFields = append(setFields, `origin_source = :origin_source`)
+
+	supplierID, err = strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid supplierID %q: %w", value, err)
 	}
-	if len(strings.TrimSpace(row[26])) != 0 {
-		switch strings.ToUpper(row[26]) {
-		case "YES":
-			sd.HonestCivilDebtor = sql.NullBool{Bool: true, Valid: true}
-		case "NO":
-			sd.HonestCivilDebtor = sql.NullBool{Bool: false, Valid: true}
-		}
-		setFields = append(setFields, `honest_civil_debtor = :honest_civil_debtor`)
-	}
-	if len(strings.TrimSpace(row[27])) != 0 {
-		switch strings.ToUpper(row[27]) {
-		case "YES":
-			sd.InvoiceUnderNinja = sql.NullBool{Bool: true, Valid: true}
-		case "NO":
-			sd.InvoiceUnderNinja = sql.NullBool{Bool: false, Valid: true}
-		}
-		setFields = append(setFields, `invoice_under_ninja = :invoice_under_ninja`)
+
+	if supplierID == 0 {
+		return 0, errors.New("supplierID is empty")
 	}
 
-	updateSupplierDetailQuery = fmt.Sprintf(updateSupplierDetailQuery, strings.Join(setFields, ", "))
 	return
 }
 
-func getNumberEmployeeRangeID(name string) int64 {
-	switch name {
-	case "<50":
-		return 1
-	case "50-99":
-		return 2
-	case "100-499":
-		return 3
-	case "500-999":
-		return 4
-	case "1000-4999":
-		return 5
-	case ">=5000":
-		return 6
-	default:
-		return 0
+// BulkUpdate writes the row to the suppliers, supplier_details and bank_account_details tables in a single transaction,
+// and syncs supplier_categories when the row lists categories. A supplier updated after the as-of time of the row is
+// not written unless the run is forced.
+// It stops at the first failing statement and returns a *RowError; the transaction is then rolled back as a whole.
+func BulkUpdate(dbInstance DB, cates *categories, row sheetRow, opts Options, result *report.Row) error {
+	supplierID, err := parseSupplierID(row)
+	if err != nil {
+		return &RowError{Stage: report.StageParse, Err: err}
 	}
-}
+	result.SupplierID = supplierID
 
-func prepareBankAccountDetailInsertSQL(ba *models.BankAccountDetails, row []string) (insertBankAccountQuery string) {
-	insertBankAccountQuery = `INSERT INTO bank_account_details (%s) VALUES (%s)`
-	setFields := []string{`:supplier_id`}
-	columns := []string{`supplier_id`}
-	if len(strings.TrimSpace(row[29])) != 0 {
-		ba.AccountType = sql.NullString{String: strings.TrimSpace(row[29]), Valid: true}
-		setFields = append(setFields, `:account_type`)
-		columns = append(columns, `account_type`)
-	}
-	if len(strings.TrimSpace(row[30])) != 0 {
-		ba.AccountHolderName = sql.NullString{String: strings.TrimSpace(row[30]), Valid: true}
-		setFields = append(setFields, `:account_holder_name`)
-		columns = append(columns, `account_holder_name`)
-	}
-	if len(strings.TrimSpace(row[31])) != 0 {
-		ba.AccountNumber = sql.NullString{String: strings.TrimSpace(row[31]), Valid: true}
-		setFields = append(setFields, `:account_number`)
-		columns = append(columns, `account_number`)
-	}
-	if len(strings.TrimSpace(row[32])) != 0 {
-		ba.BankName = sql.NullString{String: strings.TrimSpace(row[32]), Valid: true}
-		setFields = append(setFields, `:bank_name`)
-		columns = append(columns, `bank_name`)
-	}
-	if len(strings.TrimSpace(row[33])) != 0 {
-		ba.SwiftCode = sql.NullString{String: strings.TrimSpace(row[33]), Valid: true}
-		setFields = append(setFields, `:swift_code`)
-		columns = append(columns, `swift_code`)
-	}
-	if len(strings.TrimSpace(row[34])) != 0 {
-		ba.BankAddress = sql.NullString{String: strings.TrimSpace(row[34]), Valid: true}
-		setFields = append(setFields, `:bank_address`)
-		columns = append(columns, `bank_address`)
-	}
-	if len(strings.TrimSpace(row[35])) != 0 {
-		ba.SupplierCompanyAddress = sql.NullString{String: strings.TrimSpace(row[35]), Valid: true}
-		setFields = append(setFields, `:supplier_company_address`)
-		columns = append(columns, `supplier_company_address`)
-	}
-
-	insertBankAccountQuery = fmt.Sprintf(insertBankAccountQuery, strings.Join(columns, ", "), strings.Join(setFields, ", "))
-	return
-}
+	/*Init Supplier and related models*/
+	supplierBean := &models.Supplier{Id: supplierID}
+	supplierDetailBean := &models.SupplierDetail{SupplierId: supplierID}
+	bankAccountBean := &models.BankAccountDetails{SupplierId: supplierID}
 
-func prepareBankAccountDetailUpdateSQL(ba *models.BankAccountDetails, row []string) (updateBankAccountQuery string) {
-	updateBankAccountQuery = `UPDATE bank_account_details SET %s WHERE supplier_id = :supplier_id`
-	setFields := []string{}
-	if len(strings.TrimSpace(row[29])) != 0 {
-		ba.AccountType = sql.NullString{String: strings.TrimSpace(row[29]), Valid: true}
-		setFields = append(setFields, `account_type = :account_type`)
-	}
-	if len(strings.TrimSpace(row[30])) != 0 {
-		ba.AccountHolderName = sql.NullString{String: strings.TrimSpace(row[30]), Valid: true}
-		setFields = append(setFields, `account_holder_name = :account_holder_name`)
-	}
-	if len(strings.TrimSpace(row[31])) != 0 {
-		ba.AccountNumber = sql.NullString{String: strings.TrimSpace(row[31]), Valid: true}
-		setFields = append(setFields, `account_number = :account_number`)
+	isBankAccountExisted, err := isBankInformationExist(dbInstance, supplierID)
+	if err != nil {
+		return &RowError{Stage: report.StageDB, Table: mapping.TableBankAccountDetails, Err: err}
 	}
-	if len(strings.TrimSpace(row[32])) != 0 {
-		ba.BankName = sql.NullString{String: strings.TrimSpace(row[32]), Valid: true}
-		setFields = append(setFields, `bank_name = :bank_name`)
+
+	/*Prepare Supplier updation query*/
+	updateSupplierQuery, supplierColumns, supplierErr := prepareSupplierUpdateSQL(supplierBean, row)
+	updateSupplierDetailQuery, supplierDetailColumns, supplierDetailErr := prepareSupplierDetailUpdateSQL(supplierDetailBean, row)
+
+	var (
+		bankAccountQuery   string
+		bankAccountColumns []string
+		bankAccountErr     error
+	)
+	if isBankAccountExisted {
+		bankAccountQuery, bankAccountColumns, bankAccountErr = prepareBankAccountDetailUpdateSQL(bankAccountBean, row)
+	} else {
+		bankAccountQuery, bankAccountColumns, bankAccountErr = prepareBankAccountDetailInsertSQL(bankAccountBean, row)
 	}
-	if len(strings.TrimSpace(row[33])) != 0 {
-		ba.SwiftCode = sql.NullString{String: strings.TrimSpace(row[33]), Valid: true}
-		setFields = append(setFields, `swift_code = :swift_code`)
+
+	categoryIDs, categoryErr := cates.resolve(row.get(colCategories))
+	asOf, asOfErr := rowAsOf(row, opts.AsOf)
+	if err = errors.Join(supplierErr, supplierDetailErr, bankAccountErr, categoryErr, asOfErr); err != nil {
+		return &RowError{Stage: report.StageParse, Err: err}
 	}
-	if len(strings.TrimSpace(row[34])) != 0 {
-		ba.BankAddress = sql.NullString{String: strings.TrimSpace(row[34]), Valid: true}
-		setFields = append(setFields, `bank_address = :bank_address`)
+
+	// the insert always carries supplier_id and created_at, a new bank account is only created when the sheet has bank data
+	isBankAccountInsert := !isBankAccountExisted && len(bankAccountColumns) > 2
+
+	/*Validate the populated models before touching the DB*/
+	if err = errors.Join(
+		validateBean(mapping.TableSuppliers, supplierBean, supplierColumns, false),
+		validateBean(mapping.TableSupplierDetails, supplierDetailBean, supplierDetailColumns, false),
+		validateBean(mapping.TableBankAccountDetails, bankAccountBean, bankAccountColumns, isBankAccountInsert),
+	); err != nil {
+		return &RowError{Stage: report.StageValidation, Err: err}
 	}
-	if len(strings.TrimSpace(row[35])) != 0 {
-		ba.SupplierCompanyAddress = sql.NullString{String: strings.TrimSpace(row[35]), Valid: true}
-		setFields = append(setFields, `supplier_company_address = :supplier_company_address`)
+
+	/*Execute Supplier updation query on DB*/
+	tx, err := dbInstance.Beginx()
+	if err != nil {
+		return &RowError{Stage: report.StageDB, Err: fmt.Errorf("cannot begin DB transaction: %w", err)}
 	}
 
-	updateBankAccountQuery = fmt.Sprintf(updateBankAccountQuery, strings.Join(setFields, ", "))
-	return
-}
+	if err = refuseConflicts(tx, supplierID, asOf, opts, result); err != nil {
+		return err
+	}
 
-func getMostChildCateMap(dbInstance *sqlx.DB) map[string]uint {
-	type Cate struct {
-		Id   uint   `db:"category_id"`
-		Name string `db:"name"`
+	before, err := loadCurrentState(tx, supplierID)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Err: err})
 	}
 
-	cate := Cate{}
-	cateMap := map[string]uint{}
-	rows, err := dbInstance.Queryx(`SELECT c.* FROM categories c 
-		WHERE c.category_id NOT IN (
-			SELECT c2.parent_id FROM categories c2 
-			WHERE c2.deleted_at IS NULL
-			GROUP BY c2.parent_id
-		) AND c.deleted_at IS NULL;`)
+	var affected int64
+	if len(supplierColumns) == 0 {
+		err = checkSupplierExist(tx, supplierID)
+	} else if affected, err = execSupplierUpdate(tx, updateSupplierQuery, supplierBean); err == nil && affected == 0 {
+		err = fmt.Errorf("%w: id %d", ErrSupplierNotFound, supplierID)
+	}
 	if err != nil {
-		log.Panicln(err)
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableSuppliers, Err: err})
+	}
+	if len(supplierColumns) > 0 {
+		result.AddAction(mapping.TableSuppliers, report.ActionUpdate, affected, supplierColumns)
 	}
 
-	for rows.Next() {
-		err := rows.StructScan(&cate)
+	if len(supplierDetailColumns) > 0 {
+		if affected, err = execSupplierDetailUpdate(tx, updateSupplierDetailQuery, supplierDetailBean); err == nil && affected == 0 {
+			err = fmt.Errorf("%w: no supplier_details for supplier %d", ErrSupplierNotFound, supplierID)
+		}
 		if err != nil {
-			log.Panicln(err)
+			return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableSupplierDetails, Err: err})
 		}
+		result.AddAction(mapping.TableSupplierDetails, report.ActionUpdate, affected, supplierDetailColumns)
+	}
 
-		cateMap[cate.Name] = cate.Id
+	switch {
+	case isBankAccountExisted && len(bankAccountColumns) > 0:
+		if affected, err = execBankAccountUpdate(tx, bankAccountQuery, bankAccountBean); err == nil && affected == 0 {
+			err = fmt.Errorf("%w: no bank_account_details for supplier %d", ErrSupplierNotFound, supplierID)
+		}
+		if err != nil {
+			return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableBankAccountDetails, Err: err})
+		}
+		result.AddAction(mapping.TableBankAccountDetails, report.ActionUpdate, affected, bankAccountColumns)
+	case isBankAccountInsert:
+		if affected, err = execBankAccountInsert(tx, bankAccountQuery, bankAccountBean); err != nil {
+			return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableBankAccountDetails, Err: err})
+		}
+		result.AddAction(mapping.TableBankAccountDetails, report.ActionInsert, affected, bankAccountColumns)
+	}
+
+	var currentCategoryIDs []uint
+	if len(categoryIDs) > 0 {
+		if currentCategoryIDs, err = syncSupplierCategories(tx, supplierID, categoryIDs, result); err != nil {
+			return rollback(tx, result, &RowError{Stage: report.StageDB, Table: tableSupplierCategories, Err: err})
+		}
+	}
+
+	result.Changes = diffFields(tx.Mapper, mapping.TableSuppliers, before.supplier, supplierBean, supplierColumns)
+	result.Changes = append(result.Changes, diffFields(tx.Mapper, mapping.TableSupplierDetails, before.supplierDetail, supplierDetailBean, supplierDetailColumns)...)
+	if isBankAccountExisted || isBankAccountInsert {
+		result.Changes = append(result.Changes, diffFields(tx.Mapper, mapping.TableBankAccountDetails, before.bankAccount, bankAccountBean, bankAccountColumns)...)
+	}
+	if len(categoryIDs) > 0 {
+		result.Changes = append(result.Changes, categoryChanges(currentCategoryIDs, categoryIDs)...)
 	}
 
-	return cateMap
+	return finishRow(tx, opts, result)
 }
 
-func isBankInformationExist(dbInstance *sqlx.DB, supplierID int64) bool {
-	sql := `SELECT bad.*
-	FROM bank_account_details bad
-	WHERE bad.supplier_id = ? AND bad.deleted_at IS NULL;`
-	rows, err := dbInstance.Queryx(sql, supplierID)
-	if err != nil {
-		log.Panicln(err)
-		return false
+// finishRow commits the row transaction, or rolls it back on a dry run. The actions and changes of the row are
+// dropped when the commit fails.
+func finishRow(tx *sqlx.Tx, opts Options, result *report.Row) error {
+	if opts.DryRun {
+		if err := tx.Rollback(); err != nil {
+			return &RowError{Stage: report.StageDB, Err: fmt.Errorf("cannot rollback DB transaction: %w", err)}
+		}
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		result.Actions, result.Changes = nil, nil
+		return &RowError{Stage: report.StageDB, Err: fmt.Errorf("cannot commit DB transaction: %w", err)}
 	}
 
-	return rows.Next()
+	return nil
 }
 
-func checkSupplierCategoryUpdated(dbInstance *sqlx.DB, supplierID int64, reqCateName string) bool {
-	if len(strings.TrimSpace(reqCateName)) == 0 {
-		return true
+// rollback undoes the row transaction after rowErr, and drops the actions and changes of the row since none of them
+// is kept
+func rollback(tx *sqlx.Tx, result *report.Row, rowErr *RowError) error {
+	result.Actions, result.Changes = nil, nil
+	if err := tx.Rollback(); err != nil {
+		rowErr.Err = errors.Join(rowErr.Err, fmt.Errorf("rollback failed: %w", err))
 	}
 
-	slc := strings.Split(reqCateName, ",")
-	for i := range slc {
-		slc[i] = strings.TrimSpace(slc[i])
+	return rowErr
+}
+
+func checkSupplierExist(q sqlx.Queryer, supplierID int64) error {
+	var count int
+	if err := sqlx.Get(q, &count, `SELECT COUNT(*) FROM suppliers WHERE id = ? AND deleted_at IS NULL;`, supplierID); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("%w: id %d", ErrSupplierNotFound, supplierID)
 	}
 
-	slc = append(slc, reqCateName)
+	return nil
+}
+
+func execSupplierUpdate(tx *sqlx.Tx, updateSupplierQuery string, supplierBean *models.Supplier) (affected int64, err error) {
+	return execNamed(tx, "execSupplierUpdate", updateSupplierQuery, supplierBean)
+}
+
+func execSupplierDetailUpdate(tx *sqlx.Tx, updateSupplierDetailQuery string, supplierDetailBean *models.SupplierDetail) (affected int64, err error) {
+	return execNamed(tx, "execSupplierDetailUpdate", updateSupplierDetailQuery, supplierDetailBean)
+}
 
-	type SupplierCate struct {
-		Id   uint   `db:"category_id"`
-		Name string `db:"name"`
+func execBankAccountUpdate(tx *sqlx.Tx, updateBankAccountQuery string, bankAccountBean *models.BankAccountDetails) (affected int64, err error) {
+	return execNamed(tx, "execBankAccountUpdate", updateBankAccountQuery, bankAccountBean)
+}
+
+func execBankAccountInsert(tx *sqlx.Tx, insertBankAccountQuery string, bankAccountBean *models.BankAccountDetails) (affected int64, err error) {
+	return execNamed(tx, "execBankAccountInsert", insertBankAccountQuery, bankAccountBean)
+}
+
+// execNamed runs the named query with the bean and returns the number of rows it affected
+func execNamed(tx *sqlx.Tx, name, query string, bean any) (affected int64, err error) {
+	var result sql.Result
+	if result, err = tx.NamedExec(query, bean); err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
 	}
 
-	query, args, err := sqlx.In(`SELECT sc.category_id, c.name FROM supplier_categories sc 
-	JOIN categories c ON c.category_id = sc.category_id AND c.deleted_at IS NULL AND c.name IN (?)
-	WHERE sc.supplier_id = ?
-	AND sc.deleted_at IS NULL;`, slc, supplierID)
-	if err != nil {
-		log.Panicln(err)
+	if affected, err = result.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("%s: RowsAffected: %w", name, err)
 	}
 
-	query = dbInstance.Rebind(query)
-	supCate := SupplierCate{}
-	rows, err := dbInstance.Queryx(query, args...)
-	if err != nil {
-		log.Panicln(err)
+	return affected, nil
+}
+
+func prepareSupplierUpdateSQL(s *models.Supplier, row sheetRow) (updateSupplierQuery string, columns []string, err error) {
+	columns, err = row.apply(mapping.TableSuppliers, s)
+	updateSupplierQuery = fmt.Sprintf(`UPDATE suppliers SET %s WHERE id = :id AND deleted_at IS NULL`, strings.Join(setClauses(columns), ", "))
+	return
+}
+
+func prepareSupplierDetailUpdateSQL(sd *models.SupplierDetail, row sheetRow) (updateSupplierDetailQuery string, columns []string, err error) {
+	columns, err = row.apply(mapping.TableSupplierDetails, sd)
+	updateSupplierDetailQuery = fmt.Sprintf(`UPDATE supplier_details SET %s WHERE supplier_id = :supplier_id AND deleted_at IS NULL`, strings.Join(setClauses(columns), ", "))
+	return
+}
+
+// setClauses turns the columns into the "column = :column" assignments of an UPDATE statement
+func setClauses(columns []string) []string {
+	clauses := make([]string, 0, len(columns))
+	for _, column := range columns {
+		clauses = append(clauses, fmt.Sprintf("%s = :%s", column, column))
 	}
 
-	isExisted := false
-	for rows.Next() {
-		err := rows.StructScan(&supCate)
-		if err != nil {
-			log.Panicln(err)
-		}
+	return clauses
+}
+
+func prepareBankAccountDetailInsertSQL(ba *models.BankAccountDetails, row sheetRow) (insertBankAccountQuery string, columns []string, err error) {
+	columns, err = row.apply(mapping.TableBankAccountDetails, ba)
+	ba.CreatedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	columns = append([]string{`supplier_id`}, append(columns, `created_at`)...)
+	insertBankAccountQuery = insertSQL(mapping.TableBankAccountDetails, columns)
+	return
+}
 
-		isExisted = true
+func prepareBankAccountDetailUpdateSQL(ba *models.BankAccountDetails, row sheetRow) (updateBankAccountQuery string, columns []string, err error) {
+	columns, err = row.apply(mapping.TableBankAccountDetails, ba)
+	updateBankAccountQuery = fmt.Sprintf(`UPDATE bank_account_details SET %s WHERE supplier_id = :supplier_id AND deleted_at IS NULL`, strings.Join(setClauses(columns), ", "))
+	return
+}
+
+func isBankInformationExist(q sqlx.Queryer, supplierID int64) (bool, error) {
+	var count int
+	err := sqlx.Get(q, &count, `SELECT COUNT(*)
+	FROM bank_account_details bad
+	WHERE bad.supplier_id = ? AND bad.deleted_at IS NULL;`, supplierID)
+	if err != nil {
+		return false, fmt.Errorf("isBankInformationExist: %w", err)
 	}
 
-	return isExisted
+	return count > 0, nil
 }
@@ -0,0 +1,613 @@
+package imports
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+func TestMain(m *testing.M) {
+	// SQLite locks the whole database while a transaction writes and has no FOR UPDATE
+	for i := range conflictQueries {
+		conflictQueries[i].query = strings.Replace(conflictQueries[i].query, " FOR UPDATE", "", 1)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testHeaders are the header cells of the test sheets, on row 2 above the data rows
+var testHeaders = []string{
+	"Supplier ID", "Company Name", "Entity", "Country", "Contact Person", "Contact Number", "Origin Source",
+	"Margin (%)", "Categories", "Bank Name", "Action", "Delete Reason", "As Of",
+	"Import Status", "Import Timestamp", "Import Error", "Alternate Company Name", "Status", "Email Address",
+}
+
+// newTestDB returns a SQLite database with the schema of testdata/schema.sql and a supplier with ID 1, its details
+// and bank account, last updated on 2024-01-15
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "suppliers.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	schema, err := os.ReadFile(filepath.Join("testdata", "schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbInstance := sqlx.NewDb(database, "sqlite3").Unsafe()
+	mustExec(t, dbInstance, string(schema))
+	mustExec(t, dbInstance, `INSERT INTO categories (category_id, name, parent_id) VALUES
+		(1, 'Toys', NULL), (2, 'Home', NULL), (3, 'Kitchen', 2);`)
+	mustExec(t, dbInstance, `INSERT INTO supplier_tiers (id, name) VALUES (1, 'Gold');`)
+	mustExec(t, dbInstance, `INSERT INTO supplier_classifications (id, name) VALUES (1, 'Manufacturer');`)
+	mustExec(t, dbInstance, `INSERT INTO number_of_employees_ranges (id, name) VALUES (1, '<50');`)
+	mustExec(t, dbInstance, `INSERT INTO suppliers (id, company_name, alternate_company_name, entity, country, contact_person,
+		contact_number, status, updated_at) VALUES (1, 'Acme', 'Acme Group', 'Acme Ltd', 'China', 'Li Wei', '+86 123', 'Active', ?);`,
+		testUpdatedAt)
+	mustExec(t, dbInstance, `INSERT INTO supplier_details (supplier_id, origin_source, margin_in_percentage, updated_at)
+		VALUES (1, 'Fair', 10, ?);`, testUpdatedAt)
+	mustExec(t, dbInstance, `INSERT INTO bank_account_details (supplier_id, bank_name, updated_at)
+		VALUES (1, 'Bank of China', ?);`, testUpdatedAt)
+	mustExec(t, dbInstance, `INSERT INTO supplier_categories (supplier_id, category_id) VALUES (1, 3);`)
+
+	return dbInstance
+}
+
+// testUpdatedAt is when the records of the test supplier were last updated
+var testUpdatedAt = time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC)
+
+func mustExec(t *testing.T, dbInstance *sqlx.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := dbInstance.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// newTestSheet returns a sheet with testHeaders on row 2 and the rows, cells by header, from row 3
+func newTestSheet(rows ...map[string]string) *MemorySource {
+	src := &MemorySource{Rows: [][]string{{"Suppliers"}, testHeaders}}
+	for _, cells := range rows {
+		row := make([]string, len(testHeaders))
+		for header, cell := range cells {
+			row[headerIndex(header)] = cell
+		}
+		src.Rows = append(src.Rows, row)
+	}
+
+	return src
+}
+
+func headerIndex(header string) int {
+	for i, h := range testHeaders {
+		if h == header {
+			return i
+		}
+	}
+
+	panic("unknown test header " + header)
+}
+
+// testCell returns the A1 notation of the cell of the header on the sheet row
+func testCell(header string, row int) string {
+	return columnName(headerIndex(header)) + strconv.Itoa(row)
+}
+
+// readOnlySource hides the CellWriter of a MemorySource, like the CSV and XLSX sources
+type readOnlySource struct {
+	src *MemorySource
+}
+
+func (s readOnlySource) ReadRows(readRange string) ([][]string, error) {
+	return s.src.ReadRows(readRange)
+}
+
+func testOptions(t *testing.T) Options {
+	return Options{HeaderRow: 2, Operator: "tester", ReportFile: filepath.Join(t.TempDir(), "report.json")}
+}
+
+func loadReport(t *testing.T, path string) *report.Report {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &report.Report{}
+	if err = json.Unmarshal(b, r); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func queryString(t *testing.T, dbInstance *sqlx.DB, query string, args ...any) string {
+	t.Helper()
+
+	var value sql.NullString
+	if err := dbInstance.Get(&value, query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+
+	return value.String
+}
+
+func TestImportRows(t *testing.T) {
+	newSupplier := map[string]string{
+		"Company Name": "Bolt", "Entity": "Bolt Co", "Country": "China", "Contact Person": "Wang Fang",
+		"Contact Number": "+86 456", "Origin Source": "Canton Fair", "Categories": "Toys",
+	}
+
+	tests := []struct {
+		name string
+		rows []map[string]string
+		// readOnly imports the rows from a source the result cannot be written back to
+		readOnly   bool
+		opts       func(*Options)
+		wantErr    error
+		wantStatus []string
+		check      func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report)
+	}{
+		{
+			name:       "update",
+			rows:       []map[string]string{{"Supplier ID": "1", "Company Name": "Acme Toys", "Margin (%)": "12.5%", "Categories": "Toys", "Bank Name": "ICBC"}},
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme Toys" {
+					t.Errorf("company_name = %q, want Acme Toys", got)
+				}
+				if got := queryString(t, dbInstance, `SELECT margin_in_percentage FROM supplier_details WHERE supplier_id = 1`); got != "13" {
+					t.Errorf("margin_in_percentage = %s, want 13", got)
+				}
+				if got := queryString(t, dbInstance, `SELECT bank_name FROM bank_account_details WHERE supplier_id = 1`); got != "ICBC" {
+					t.Errorf("bank_name = %q, want ICBC", got)
+				}
+				if got := queryString(t, dbInstance, `SELECT group_concat(category_id) FROM supplier_categories
+					WHERE supplier_id = 1 AND deleted_at IS NULL`); got != "1" {
+					t.Errorf("categories = %s, want 1", got)
+				}
+
+				row := r.Rows[0]
+				if !hasChange(row, "suppliers", "company_name", "Acme", "Acme Toys") {
+					t.Errorf("changes = %+v, want suppliers.company_name Acme -> Acme Toys", row.Changes)
+				}
+				if len(row.Warnings) != 1 || !strings.Contains(row.Warnings[0], "12.5%") {
+					t.Errorf("warnings = %q, want the rounded margin", row.Warnings)
+				}
+				if got := src.Written[testCell("Import Status", 3)]; got != report.StatusOK {
+					t.Errorf("written status = %v, want OK", got)
+				}
+			},
+		},
+		{
+			name:       "insert",
+			rows:       []map[string]string{newSupplier},
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT created_by FROM suppliers WHERE company_name = 'Bolt'`); got != "tester" {
+					t.Errorf("created_by = %q, want tester", got)
+				}
+				if got := queryString(t, dbInstance, `SELECT origin_source FROM supplier_details WHERE supplier_id = 2`); got != "Canton Fair" {
+					t.Errorf("origin_source = %q, want Canton Fair", got)
+				}
+				if got := src.Written[testCell("Supplier ID", 3)]; got != int64(2) {
+					t.Errorf("written supplier ID = %v, want 2", got)
+				}
+				if r.Rows[0].SupplierID != 2 {
+					t.Errorf("report supplier ID = %d, want 2", r.Rows[0].SupplierID)
+				}
+			},
+		},
+		{
+			name:       "insert from a source that cannot be written back",
+			rows:       []map[string]string{newSupplier},
+			readOnly:   true,
+			wantErr:    errRowsNotImported,
+			wantStatus: []string{report.StatusSkipped},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT COUNT(*) FROM suppliers`); got != "1" {
+					t.Errorf("suppliers = %s, want 1", got)
+				}
+				if errs := r.Rows[0].Errors; len(errs) != 1 || errs[0].Message != ErrNoWriteBack.Error() {
+					t.Errorf("errors = %+v, want %v", errs, ErrNoWriteBack)
+				}
+			},
+		},
+		{
+			name:       "insert without write-back allowed",
+			rows:       []map[string]string{newSupplier},
+			readOnly:   true,
+			opts:       func(opts *Options) { opts.InsertWithoutWriteBack = true },
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT COUNT(*) FROM suppliers`); got != "2" {
+					t.Errorf("suppliers = %s, want 2", got)
+				}
+			},
+		},
+		{
+			name:       "delete",
+			rows:       []map[string]string{{"Supplier ID": "1", "Action": "delete", "Delete Reason": "Duplicated Supplier"}},
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT deleted_by FROM suppliers WHERE id = 1 AND deleted_at IS NOT NULL`); got != "tester" {
+					t.Errorf("deleted_by = %q, want tester", got)
+				}
+				for _, table := range []string{"supplier_details", "bank_account_details"} {
+					if got := queryString(t, dbInstance, `SELECT COUNT(*) FROM `+table+` WHERE deleted_at IS NULL`); got != "0" {
+						t.Errorf("%s not deleted = %s, want 0", table, got)
+					}
+				}
+				if row := r.Rows[0]; row.DeleteReason != "Duplicated Supplier" || row.DeletedBy != "tester" {
+					t.Errorf("report deleted by %q for %q, want tester for Duplicated Supplier", row.DeletedBy, row.DeleteReason)
+				}
+			},
+		},
+		{
+			name:    "delete without report",
+			rows:    []map[string]string{{"Supplier ID": "1", "Action": "delete", "Delete Reason": "Duplicated Supplier"}},
+			opts:    func(opts *Options) { opts.ReportFile = "" },
+			wantErr: ErrDeleteWithoutReport,
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT COUNT(*) FROM suppliers WHERE deleted_at IS NULL`); got != "1" {
+					t.Errorf("suppliers not deleted = %s, want 1", got)
+				}
+			},
+		},
+		{
+			name:       "dry run",
+			rows:       []map[string]string{{"Supplier ID": "1", "Company Name": "Acme Toys", "Categories": "Toys"}, newSupplier},
+			opts:       func(opts *Options) { opts.DryRun = true },
+			wantStatus: []string{report.StatusOK, report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme" {
+					t.Errorf("company_name = %q, want Acme", got)
+				}
+				if got := queryString(t, dbInstance, `SELECT COUNT(*) FROM suppliers`); got != "1" {
+					t.Errorf("suppliers = %s, want 1", got)
+				}
+				if len(src.Written) > 0 {
+					t.Errorf("written = %v, want nothing on a dry run", src.Written)
+				}
+				if !r.DryRun || !hasChange(r.Rows[0], "suppliers", "company_name", "Acme", "Acme Toys") {
+					t.Errorf("dry run %v changes %+v, want suppliers.company_name Acme -> Acme Toys", r.DryRun, r.Rows[0].Changes)
+				}
+			},
+		},
+		{
+			name:       "conflict",
+			rows:       []map[string]string{{"Supplier ID": "1", "Company Name": "Acme Toys", "As Of": "2024-01-10 08:00:00"}},
+			wantErr:    errRowsNotImported,
+			wantStatus: []string{report.StatusSkipped},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme" {
+					t.Errorf("company_name = %q, want Acme", got)
+				}
+				if errs := r.Rows[0].Errors; len(errs) != 1 || errs[0].Stage != report.StageConflict {
+					t.Errorf("errors = %+v, want a conflict", errs)
+				}
+				if got := src.Written[testCell("Import Status", 3)]; got != report.StatusSkipped {
+					t.Errorf("written status = %v, want SKIPPED", got)
+				}
+			},
+		},
+		{
+			name:       "conflict forced",
+			rows:       []map[string]string{{"Supplier ID": "1", "Company Name": "Acme Toys", "As Of": "2024-01-10 08:00:00"}},
+			opts:       func(opts *Options) { opts.Force = true },
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme Toys" {
+					t.Errorf("company_name = %q, want Acme Toys", got)
+				}
+				if len(r.Rows[0].Conflicts) != 3 || r.Summary.Conflicts != 1 {
+					t.Errorf("conflicts = %q, want the 3 records of the supplier", r.Rows[0].Conflicts)
+				}
+			},
+		},
+		{
+			name:       "no conflict",
+			rows:       []map[string]string{{"Supplier ID": "1", "Company Name": "Acme Toys", "As Of": "2024-01-20"}},
+			wantStatus: []string{report.StatusOK},
+		},
+		{
+			name:       "unknown supplier",
+			rows:       []map[string]string{{"Supplier ID": "99", "Company Name": "Nobody"}, {"Supplier ID": "1", "Company Name": "Acme Toys"}},
+			wantErr:    errRowsNotImported,
+			wantStatus: []string{report.StatusFailed, report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := src.Written[testCell("Import Error", 3)]; !strings.Contains(got.(string), ErrSupplierNotFound.Error()) {
+					t.Errorf("written error = %v, want %v", got, ErrSupplierNotFound)
+				}
+				if got := src.Written[testCell("Import Error", 4)]; got != "" {
+					t.Errorf("written error = %q, want none", got)
+				}
+			},
+		},
+		{
+			name:       "email in capitals",
+			rows:       []map[string]string{{"Supplier ID": "1", "Email Address": " Sales@Acme.com "}},
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT email_address FROM supplier_details WHERE supplier_id = 1`); got != "sales@acme.com" {
+					t.Errorf("email_address = %q, want sales@acme.com", got)
+				}
+			},
+		},
+		{
+			name:       "null value",
+			rows:       []map[string]string{{"Supplier ID": "1", "Alternate Company Name": "<NULL>"}},
+			wantStatus: []string{report.StatusOK},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if got := queryString(t, dbInstance, `SELECT coalesce(alternate_company_name, 'NULL') FROM suppliers WHERE id = 1`); got != "NULL" {
+					t.Errorf("alternate_company_name = %q, want NULL", got)
+				}
+				if !hasChange(r.Rows[0], "suppliers", "alternate_company_name", "Acme Group", "NULL") {
+					t.Errorf("changes = %+v, want suppliers.alternate_company_name Acme Group -> NULL", r.Rows[0].Changes)
+				}
+				if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme" {
+					t.Errorf("company_name = %q, want Acme", got)
+				}
+			},
+		},
+		{
+			name:       "null value of a non-nullable field",
+			rows:       []map[string]string{{"Supplier ID": "1", "Company Name": "<NULL>", "Alternate Company Name": "<NULL>"}},
+			wantErr:    errRowsNotImported,
+			wantStatus: []string{report.StatusSkipped},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if errs := r.Rows[0].Errors; len(errs) != 1 || !strings.Contains(errs[0].Message, "company_name does not accept NULL") {
+					t.Errorf("errors = %+v, want the company name refused", errs)
+				}
+				if got := queryString(t, dbInstance, `SELECT company_name || '/' || alternate_company_name FROM suppliers WHERE id = 1`); got != "Acme/Acme Group" {
+					t.Errorf("names = %q, want Acme/Acme Group", got)
+				}
+			},
+		},
+		{
+			name:       "invalid cell",
+			rows:       []map[string]string{{"Supplier ID": "1", "Margin (%)": "150%"}},
+			wantErr:    errRowsNotImported,
+			wantStatus: []string{report.StatusSkipped},
+			check: func(t *testing.T, dbInstance *sqlx.DB, src *MemorySource, r *report.Report) {
+				if errs := r.Rows[0].Errors; len(errs) != 1 || errs[0].Stage != report.StageValidation {
+					t.Errorf("errors = %+v, want a validation error", errs)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbInstance := newTestDB(t)
+			src := newTestSheet(tt.rows...)
+			opts := testOptions(t)
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+
+			var rowSource RowSource = src
+			if tt.readOnly {
+				rowSource = readOnlySource{src}
+			}
+
+			err := ImportRows(dbInstance, rowSource, opts)
+			switch {
+			case tt.wantErr == errRowsNotImported:
+				if err == nil || !strings.Contains(err.Error(), "rows were not imported") {
+					t.Fatalf("ImportRows() error = %v, want rows not imported", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ImportRows() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("ImportRows() error = %v", err)
+			}
+
+			var r *report.Report
+			if len(tt.wantStatus) > 0 {
+				r = loadReport(t, opts.ReportFile)
+				if len(r.Rows) != len(tt.wantStatus) {
+					t.Fatalf("report has %d rows, want %d", len(r.Rows), len(tt.wantStatus))
+				}
+				for i, row := range r.Rows {
+					if row.Row != i+3 || row.Status != tt.wantStatus[i] {
+						t.Errorf("report row %d is row %d %s %+v, want row %d %s", i, row.Row, row.Status, row.Errors, i+3, tt.wantStatus[i])
+					}
+				}
+			}
+
+			if tt.check != nil {
+				tt.check(t, dbInstance, src, r)
+			}
+		})
+	}
+}
+
+// errRowsNotImported stands for the error ImportRows returns once the rows are processed, when some were not imported
+var errRowsNotImported = errors.New("rows were not imported")
+
+func hasChange(row *report.Row, table, field, before, after string) bool {
+	for _, change := range row.Changes {
+		if change.Table == table && change.Field == field && change.Before == before && change.After == after {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TestImportRowsStoredValues imports statuses, resolved against the ones stored in suppliers
+func TestImportRowsStoredValues(t *testing.T) {
+	dbInstance := newTestDB(t)
+	mustExec(t, dbInstance, `INSERT INTO suppliers (id, company_name, status, deleted_at) VALUES (9, 'Gone', 'Blacklisted', ?);`, testUpdatedAt)
+	sheet := newTestSheet(
+		map[string]string{"Supplier ID": "1", "Status": "blacklisted"},
+		map[string]string{"Supplier ID": "1", "Status": "Paused"},
+	)
+	opts := testOptions(t)
+
+	if err := ImportRows(dbInstance, sheet, opts); err == nil {
+		t.Fatal("ImportRows() error = nil, want row 4 not imported")
+	}
+	if got := queryString(t, dbInstance, `SELECT status FROM suppliers WHERE id = 1`); got != "Blacklisted" {
+		t.Errorf("status = %q, want Blacklisted as stored", got)
+	}
+
+	r := loadReport(t, opts.ReportFile)
+	if errs := r.Rows[1].Errors; len(errs) != 1 || !strings.Contains(errs[0].Message, `unknown value "Paused", expected one of: Active, Blacklisted`) {
+		t.Errorf("errors = %+v, want the stored statuses listed", errs)
+	}
+}
+
+func TestValidateRows(t *testing.T) {
+	dbInstance := newTestDB(t)
+	src := newTestSheet(
+		map[string]string{"Supplier ID": "1", "Company Name": "Acme Toys"},
+		map[string]string{},
+		map[string]string{"Supplier ID": "1", "Margin (%)": "150%"},
+	)
+
+	err := ValidateRows(dbInstance, src, testOptions(t))
+	if err == nil || err.Error() != "1 of 2 rows are invalid" {
+		t.Fatalf("ValidateRows() error = %v, want 1 of 2 rows are invalid", err)
+	}
+
+	if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme" {
+		t.Errorf("company_name = %q, want Acme", got)
+	}
+}
+
+func TestExportDiffRoundTrip(t *testing.T) {
+	dbInstance := newTestDB(t)
+	opts := testOptions(t)
+	sheet := &MemorySource{}
+	if err := ExportRows(dbInstance, sheet, opts, ExportFilter{}); err != nil {
+		t.Fatalf("ExportRows() error = %v", err)
+	}
+
+	reconciliation, err := DiffRows(dbInstance, sheet, opts)
+	if err != nil {
+		t.Fatalf("DiffRows() error = %v", err)
+	}
+	if len(reconciliation.Rows) != 1 || reconciliation.Rows[0].Status != report.DiffInSync {
+		t.Fatalf("reconciliation rows = %+v, want supplier 1 in sync", reconciliation.Rows)
+	}
+
+	// the exported rows are imported back as they are, their as-of time after the last update
+	if err = ImportRows(dbInstance, sheet, opts); err != nil {
+		t.Fatalf("ImportRows() error = %v", err)
+	}
+	if r := loadReport(t, opts.ReportFile); r.Summary.OK != 1 || r.Summary.Conflicts != 0 {
+		t.Errorf("summary = %+v, want 1 row OK without conflict", r.Summary)
+	}
+
+	mustExec(t, dbInstance, `UPDATE suppliers SET company_name = 'Acme Toys' WHERE id = 1`)
+	if reconciliation, err = DiffRows(dbInstance, sheet, opts); err != nil {
+		t.Fatalf("DiffRows() error = %v", err)
+	}
+	row := reconciliation.Rows[0]
+	if row.Status != report.DiffDiffers || len(row.Fields) != 1 || row.Fields[0].Field != "company_name" {
+		t.Errorf("reconciliation row = %+v, want company_name differing", row)
+	}
+}
+
+// TestExportRowsCategories exports the categories import resolves, leaving out the deleted and non-leaf ones
+func TestExportRowsCategories(t *testing.T) {
+	dbInstance := newTestDB(t)
+	mustExec(t, dbInstance, `INSERT INTO categories (category_id, name, parent_id, deleted_at) VALUES (4, 'Garden', NULL, ?);`, testUpdatedAt)
+	mustExec(t, dbInstance, `INSERT INTO supplier_categories (supplier_id, category_id) VALUES (1, 2), (1, 4);`)
+	opts := testOptions(t)
+	sheet := &MemorySource{}
+	if err := ExportRows(dbInstance, sheet, opts, ExportFilter{}); err != nil {
+		t.Fatalf("ExportRows() error = %v", err)
+	}
+
+	for i, header := range sheet.Rows[1] {
+		if header == "Categories" && sheet.Rows[2][i] != "Kitchen" {
+			t.Errorf("categories = %q, want Kitchen", sheet.Rows[2][i])
+		}
+	}
+
+	if err := ImportRows(dbInstance, sheet, opts); err != nil {
+		t.Fatalf("ImportRows() error = %v", err)
+	}
+}
+
+func TestExportRowsNarrowRange(t *testing.T) {
+	dbInstance := newTestDB(t)
+	opts := testOptions(t)
+	opts.Range = "A3:AR"
+	if err := ExportRows(dbInstance, &MemorySource{}, opts, ExportFilter{}); err == nil {
+		t.Fatal("ExportRows() error = nil, want the columns beyond the range refused")
+	}
+}
+
+// TestImportRowsInsertedColumn imports an exported sheet a column was inserted in, the mapped columns moved past
+// the width of the mapping
+func TestImportRowsInsertedColumn(t *testing.T) {
+	dbInstance := newTestDB(t)
+	opts := testOptions(t)
+	sheet := &MemorySource{}
+	if err := ExportRows(dbInstance, sheet, opts, ExportFilter{}); err != nil {
+		t.Fatalf("ExportRows() error = %v", err)
+	}
+
+	for i, row := range sheet.Rows {
+		if len(row) > 0 {
+			sheet.Rows[i] = append([]string{row[0], "Notes"}, row[1:]...)
+		}
+	}
+	header := sheet.Rows[1]
+	for i, cell := range header {
+		if cell == "Company Name" {
+			sheet.Rows[2][i] = "Acme Toys"
+		}
+	}
+
+	if err := ImportRows(dbInstance, sheet, opts); err != nil {
+		t.Fatalf("ImportRows() error = %v", err)
+	}
+	if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme Toys" {
+		t.Errorf("company_name = %q, want Acme Toys", got)
+	}
+
+	errorCell := columnName(len(header)-1) + "3"
+	if header[len(header)-1] != "Import Error" {
+		t.Fatalf("last header = %q, want Import Error", header[len(header)-1])
+	}
+	if got, ok := sheet.Written[errorCell]; !ok || got != "" {
+		t.Errorf("written %s = %#v, want the empty import error", errorCell, got)
+	}
+}
+
+func TestImportRowsHeaderOutsideRange(t *testing.T) {
+	dbInstance := newTestDB(t)
+	opts := testOptions(t)
+	sheet := newTestSheet(map[string]string{"Supplier ID": "1", "Company Name": "Acme Toys"})
+	opts.Range = "A3:" + columnName(headerIndex("Import Status"))
+
+	err := ImportRows(dbInstance, sheet, opts)
+	if err == nil || !strings.Contains(err.Error(), `"Import Error" (column P)`) {
+		t.Fatalf("ImportRows() error = %v, want Import Error outside the range", err)
+	}
+	if got := queryString(t, dbInstance, `SELECT company_name FROM suppliers WHERE id = 1`); got != "Acme" {
+		t.Errorf("company_name = %q, want Acme", got)
+	}
+}
@@ -0,0 +1,221 @@
+package imports
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+	"github.com/lk153/import-gsheet/internal/models"
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+// isNewSupplier reports whether the row has no supplier ID and creates a supplier
+func isNewSupplier(row sheetRow) bool {
+	return strings.TrimSpace(row.get(colSupplierID)) == ""
+}
+
+// checkWriteBack refuses to create the supplier of the row when its new ID cannot be written back to the source, since
+// the next run would create it again, unless the run allows it
+func checkWriteBack(src RowSource, row sheetRow, opts Options) error {
+	if opts.InsertWithoutWriteBack {
+		return nil
+	}
+
+	if _, ok := src.(CellWriter); ok {
+		if _, ok = row.header[colSupplierID]; ok {
+			return nil
+		}
+	}
+
+	return ErrNoWriteBack
+}
+
+// insertStatements holds the populated models and INSERT statements of a row without supplier ID
+type insertStatements struct {
+	supplierBean          *models.Supplier
+	supplierDetailBean    *models.SupplierDetail
+	bankAccountBean       *models.BankAccountDetails
+	supplierQuery         string
+	supplierDetailQuery   string
+	bankAccountQuery      string
+	supplierColumns       []string
+	supplierDetailColumns []string
+	bankAccountColumns    []string
+}
+
+// hasBankAccount reports whether the sheet has bank data for the new supplier,
+// the insert always carries supplier_id and created_at
+func (s *insertStatements) hasBankAccount() bool {
+	return len(s.bankAccountColumns) > 2
+}
+
+// prepareInsert populates the models of a new supplier from the row and validates them as a whole
+func prepareInsert(mapper *reflectx.Mapper, row sheetRow, operator string) (*insertStatements, error) {
+	/*Init Supplier and related models*/
+	s := &insertStatements{
+		supplierBean:       &models.Supplier{CreatedBy: sql.NullString{String: operator, Valid: operator != ""}},
+		supplierDetailBean: &models.SupplierDetail{},
+		bankAccountBean:    &models.BankAccountDetails{},
+	}
+
+	/*Prepare Supplier insertion query*/
+	var supplierErr, supplierDetailErr, bankAccountErr error
+	s.supplierQuery, s.supplierColumns, supplierErr = prepareSupplierInsertSQL(s.supplierBean, row)
+	s.supplierDetailQuery, s.supplierDetailColumns, supplierDetailErr = prepareSupplierDetailInsertSQL(s.supplierDetailBean, row)
+	s.bankAccountQuery, s.bankAccountColumns, bankAccountErr = prepareBankAccountDetailInsertSQL(s.bankAccountBean, row)
+	if err := errors.Join(supplierErr, supplierDetailErr, bankAccountErr); err != nil {
+		return nil, &RowError{Stage: report.StageParse, Err: err}
+	}
+
+	/*Validate the populated models before touching the DB*/
+	err := errors.Join(
+		checkNotNull(mapper, row.mapping, mapping.TableSuppliers, s.supplierBean, s.supplierColumns),
+		checkNotNull(mapper, row.mapping, mapping.TableSupplierDetails, s.supplierDetailBean, s.supplierDetailColumns),
+		validateBean(mapping.TableSuppliers, s.supplierBean, s.supplierColumns, true),
+		validateBean(mapping.TableSupplierDetails, s.supplierDetailBean, s.supplierDetailColumns, true),
+	)
+	if s.hasBankAccount() {
+		err = errors.Join(err, validateBean(mapping.TableBankAccountDetails, s.bankAccountBean, s.bankAccountColumns, true))
+	}
+	if err != nil {
+		return nil, &RowError{Stage: report.StageValidation, Err: err}
+	}
+
+	return s, nil
+}
+
+// validateInsert runs the checks of BulkInsert on the row without touching the database
+func validateInsert(row sheetRow) error {
+	_, err := prepareInsert(dbMapper, row, "")
+	return err
+}
+
+// BulkInsert creates the supplier of a row without supplier ID together with its supplier_details, its
+// supplier_categories and, when the sheet has bank data, its bank_account_details in a single transaction.
+// The new supplier ID is set on result; like BulkUpdate it returns a *RowError when nothing is written.
+func BulkInsert(dbInstance DB, cates *categories, row sheetRow, opts Options, result *report.Row) error {
+	s, err := prepareInsert(dbMapper, row, opts.Operator)
+	if err != nil {
+		return err
+	}
+
+	categoryIDs, err := cates.resolve(row.get(colCategories))
+	if err != nil {
+		return &RowError{Stage: report.StageParse, Err: err}
+	}
+
+	/*Execute Supplier insertion query on DB*/
+	tx, err := dbInstance.Beginx()
+	if err != nil {
+		return &RowError{Stage: report.StageDB, Err: fmt.Errorf("cannot begin DB transaction: %w", err)}
+	}
+
+	supplierID, err := execSupplierInsert(tx, s.supplierQuery, s.supplierBean)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableSuppliers, Err: err})
+	}
+	result.AddAction(mapping.TableSuppliers, report.ActionInsert, 1, s.supplierColumns)
+
+	s.supplierBean.Id = supplierID
+	s.supplierDetailBean.SupplierId = supplierID
+	s.bankAccountBean.SupplierId = supplierID
+
+	affected, err := execSupplierDetailInsert(tx, s.supplierDetailQuery, s.supplierDetailBean)
+	if err != nil {
+		return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableSupplierDetails, Err: err})
+	}
+	result.AddAction(mapping.TableSupplierDetails, report.ActionInsert, affected, s.supplierDetailColumns)
+
+	if s.hasBankAccount() {
+		if affected, err = execBankAccountInsert(tx, s.bankAccountQuery, s.bankAccountBean); err != nil {
+			return rollback(tx, result, &RowError{Stage: report.StageDB, Table: mapping.TableBankAccountDetails, Err: err})
+		}
+		result.AddAction(mapping.TableBankAccountDetails, report.ActionInsert, affected, s.bankAccountColumns)
+	}
+
+	if len(categoryIDs) > 0 {
+		if _, err = syncSupplierCategories(tx, supplierID, categoryIDs, result); err != nil {
+			return rollback(tx, result, &RowError{Stage: report.StageDB, Table: tableSupplierCategories, Err: err})
+		}
+	}
+
+	result.Changes = diffFields(tx.Mapper, mapping.TableSuppliers, &models.Supplier{}, s.supplierBean, s.supplierColumns)
+	result.Changes = append(result.Changes, diffFields(tx.Mapper, mapping.TableSupplierDetails, &models.SupplierDetail{}, s.supplierDetailBean, s.supplierDetailColumns)...)
+	if s.hasBankAccount() {
+		result.Changes = append(result.Changes, diffFields(tx.Mapper, mapping.TableBankAccountDetails, &models.BankAccountDetails{}, s.bankAccountBean, s.bankAccountColumns)...)
+	}
+	result.Changes = append(result.Changes, categoryChanges(nil, categoryIDs)...)
+
+	if err = finishRow(tx, opts, result); err != nil {
+		return err
+	}
+
+	result.SupplierID = supplierID
+	return nil
+}
+
+func prepareSupplierInsertSQL(s *models.Supplier, row sheetRow) (insertSupplierQuery string, columns []string, err error) {
+	columns, err = row.apply(mapping.TableSuppliers, s)
+	s.CreatedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	columns = append(columns, `created_at`, `created_by`)
+	insertSupplierQuery = insertSQL(mapping.TableSuppliers, columns)
+	return
+}
+
+func prepareSupplierDetailInsertSQL(sd *models.SupplierDetail, row sheetRow) (insertSupplierDetailQuery string, columns []string, err error) {
+	columns, err = row.apply(mapping.TableSupplierDetails, sd)
+	sd.CreatedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	columns = append([]string{`supplier_id`}, append(columns, `created_at`)...)
+	insertSupplierDetailQuery = insertSQL(mapping.TableSupplierDetails, columns)
+	return
+}
+
+// insertSQL builds the named INSERT statement of the columns
+func insertSQL(table string, columns []string) string {
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:%s)`, table, strings.Join(columns, ", "), strings.Join(columns, ", :"))
+}
+
+// checkNotNull makes sure a new record gets a value for every mapped text column the table does not allow NULL in
+func checkNotNull(mapper *reflectx.Mapper, m *mapping.Mapping, table string, bean any, columns []string) error {
+	set := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		set[column] = true
+	}
+
+	var errs []error
+	for _, col := range m.TableColumns(table) {
+		if set[col.Field] || col.Nullable() {
+			continue
+		}
+
+		if field := mapper.FieldByName(reflect.Indirect(reflect.ValueOf(bean)), col.Field); field.Kind() == reflect.String {
+			errs = append(errs, fmt.Errorf("%s.%s: %q is required for a new supplier", table, col.Field, col.Header))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func execSupplierInsert(tx *sqlx.Tx, insertSupplierQuery string, supplierBean *models.Supplier) (supplierID int64, err error) {
+	var result sql.Result
+	if result, err = tx.NamedExec(insertSupplierQuery, supplierBean); err != nil {
+		return 0, fmt.Errorf("execSupplierInsert: %w", err)
+	}
+
+	if supplierID, err = result.LastInsertId(); err != nil {
+		return 0, fmt.Errorf("execSupplierInsert: LastInsertId: %w", err)
+	}
+
+	return supplierID, nil
+}
+
+func execSupplierDetailInsert(tx *sqlx.Tx, insertSupplierDetailQuery string, supplierDetailBean *models.SupplierDetail) (affected int64, err error) {
+	return execNamed(tx, "execSupplierDetailInsert", insertSupplierDetailQuery, supplierDetailBean)
+}
@@ -0,0 +1,66 @@
+package imports
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+)
+
+// lookupQueries select the id and name of the records the lookup columns are resolved against, by lookup table
+var lookupQueries = map[string]string{
+	"supplier_tiers":             `SELECT id, name FROM supplier_tiers WHERE deleted_at IS NULL;`,
+	"supplier_classifications":   `SELECT id, name FROM supplier_classifications WHERE deleted_at IS NULL;`,
+	"number_of_employees_ranges": `SELECT id, name FROM number_of_employees_ranges;`,
+}
+
+// storedValueQueries select the distinct values stored in a field that has no table of its own, by lookup name.
+// The lookup columns resolved against them write the value itself.
+var storedValueQueries = map[string]string{
+	"supplier_statuses":    `SELECT DISTINCT status FROM suppliers WHERE status IS NOT NULL AND status <> '';`,
+	"social_network_types": `SELECT DISTINCT social_network_type FROM suppliers WHERE social_network_type IS NOT NULL AND social_network_type <> '';`,
+}
+
+// loadLookups fills the values of the lookup columns of the mapping from the database
+func loadLookups(q sqlx.Queryer, m *mapping.Mapping) error {
+	type record struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	for _, lookup := range m.Lookups() {
+		if query, ok := storedValueQueries[lookup]; ok {
+			var stored []string
+			if err := sqlx.Select(q, &stored, query); err != nil {
+				return fmt.Errorf("load %s: %w", lookup, err)
+			}
+
+			values := make(map[string]any, len(stored))
+			for _, value := range stored {
+				values[strings.TrimSpace(value)] = value
+			}
+			m.SetLookup(lookup, values)
+			continue
+		}
+
+		query, ok := lookupQueries[lookup]
+		if !ok {
+			return fmt.Errorf("unknown lookup table %q", lookup)
+		}
+
+		var records []record
+		if err := sqlx.Select(q, &records, query); err != nil {
+			return fmt.Errorf("load %s: %w", lookup, err)
+		}
+
+		values := make(map[string]any, len(records))
+		for _, r := range records {
+			values[strings.TrimSpace(r.Name)] = r.Id
+		}
+		m.SetLookup(lookup, values)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package imports
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lk153/gsheet-go/lib"
+	"google.golang.org/api/sheets/v4"
+)
+
+/*Keys of the mapping columns the import result of a row is written to*/
+const (
+	colImportStatus    = "import_status"
+	colImportTimestamp = "import_timestamp"
+	colImportError     = "import_error"
+)
+
+// gsheetSource is the RowSource of a Google spreadsheet, the import result is written back to it
+type gsheetSource struct {
+	*lib.GSheetService
+	spreadsheetID string
+}
+
+func newGSheetSource(spreadsheetID string) (gsheetSource, error) {
+	srv, err := lib.NewGsheetServiceV2()
+	if err != nil {
+		return gsheetSource{}, fmt.Errorf("cannot connect Gsheet: %w", err)
+	}
+
+	return gsheetSource{GSheetService: srv, spreadsheetID: spreadsheetID}, nil
+}
+
+func (s gsheetSource) ReadRows(readRange string) ([][]string, error) {
+	resp, err := s.Spreadsheets.Values.Get(s.spreadsheetID, readRange).Do()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", readRange, err)
+	}
+
+	values := make([][]string, 0, len(resp.Values))
+	for _, row := range resp.Values {
+		cells := make([]string, 0, len(row))
+		for _, cell := range row {
+			cells = append(cells, fmt.Sprint(cell))
+		}
+		values = append(values, cells)
+	}
+
+	return values, nil
+}
+
+func (s gsheetSource) WriteCells(values map[string]any) error {
+	req := &sheets.BatchUpdateValuesRequest{ValueInputOption: "RAW"}
+	for rng, value := range values {
+		req.Data = append(req.Data, &sheets.ValueRange{Range: rng, Values: [][]any{{value}}})
+	}
+
+	_, err := s.Spreadsheets.Values.BatchUpdate(s.spreadsheetID, req).Do()
+	return err
+}
+
+func (s gsheetSource) WriteRows(writeRange string, rows [][]string) error {
+	if _, err := s.Spreadsheets.Values.Clear(s.spreadsheetID, writeRange, &sheets.ClearValuesRequest{}).Do(); err != nil {
+		return fmt.Errorf("clear %s: %w", writeRange, err)
+	}
+
+	values := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]any, 0, len(row))
+		for _, cell := range row {
+			cells = append(cells, cell)
+		}
+		values = append(values, cells)
+	}
+
+	_, err := s.Spreadsheets.Values.Update(s.spreadsheetID, writeRange, &sheets.ValueRange{Values: values}).ValueInputOption("RAW").Do()
+	if err != nil {
+		return fmt.Errorf("write %s: %w", writeRange, err)
+	}
+
+	return nil
+}
+
+// writeResult writes the status, the time and the error of the row to the result columns present in the sheet.
+// The ID of a supplier created from the row is written to its supplier ID cell so the next run updates it.
+func writeResult(w CellWriter, opts Options, row sheetRow, supplierID int64, err error) error {
+	results := map[string]any{
+		colImportStatus:    rowStatus(err),
+		colImportTimestamp: time.Now().Format(time.DateTime),
+		colImportError:     "",
+	}
+	if err != nil {
+		results[colImportError] = err.Error()
+	}
+	if err == nil && isNewSupplier(row) && supplierID != 0 {
+		results[colSupplierID] = supplierID
+	}
+
+	values := map[string]any{}
+	for key, value := range results {
+		if cell, ok := row.cellRange(opts, key); ok {
+			values[cell] = value
+		}
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	if err := w.WriteCells(values); err != nil {
+		ranges := make([]string, 0, len(values))
+		for rng := range values {
+			ranges = append(ranges, rng)
+		}
+		sort.Strings(ranges)
+		return fmt.Errorf("write import result to %v: %w", ranges, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lk153/import-gsheet/internal/mapping"
+	"github.com/lk153/import-gsheet/internal/report"
+)
+
+// failingWriter is a sheet refusing every write
+type failingWriter struct{}
+
+func (failingWriter) WriteCells(map[string]any) error {
+	return errors.New("quota exceeded")
+}
+
+// newTestRow returns sheet row 3 of the testHeaders layout with the cells by header
+func newTestRow(t *testing.T, headers []string, cells map[string]string) sheetRow {
+	t.Helper()
+
+	m, err := mapping.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := parseHeader(headers, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := make([]string, len(headers))
+	for header, cell := range cells {
+		row[headerIndex(header)] = cell
+	}
+
+	return sheetRow{mapping: m, header: h, cells: row, number: 3}
+}
+
+func TestWriteResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    []string
+		cells      map[string]string
+		sheet      string
+		supplierID int64
+		err        error
+		want       map[string]any
+	}{
+		{
+			name:       "ok",
+			cells:      map[string]string{"Supplier ID": "1"},
+			supplierID: 1,
+			want:       map[string]any{"N3": report.StatusOK, "P3": ""},
+		},
+		{
+			name:       "failed",
+			cells:      map[string]string{"Supplier ID": "1"},
+			supplierID: 1,
+			err:        &RowError{Stage: report.StageDB, Table: mapping.TableSuppliers, Err: ErrSupplierNotFound},
+			want:       map[string]any{"N3": report.StatusFailed, "P3": "db suppliers: supplier not found"},
+		},
+		{
+			name:       "skipped",
+			cells:      map[string]string{"Supplier ID": "1"},
+			supplierID: 1,
+			err:        &RowError{Stage: report.StageConflict, Err: ErrConflict},
+			want:       map[string]any{"N3": report.StatusSkipped, "P3": "conflict: " + ErrConflict.Error()},
+		},
+		{
+			name:       "new supplier",
+			cells:      map[string]string{"Company Name": "Bolt"},
+			supplierID: 42,
+			want:       map[string]any{"A3": int64(42), "N3": report.StatusOK, "P3": ""},
+		},
+		{
+			name:  "new supplier failed",
+			cells: map[string]string{"Company Name": "Bolt"},
+			err:   &RowError{Stage: report.StageValidation, Err: errors.New("company_name is required")},
+			want:  map[string]any{"N3": report.StatusSkipped, "P3": "validation: company_name is required"},
+		},
+		{
+			name:       "named sheet",
+			cells:      map[string]string{"Supplier ID": "1"},
+			sheet:      "To Update on DB",
+			supplierID: 1,
+			want:       map[string]any{"'To Update on DB'!N3": report.StatusOK, "'To Update on DB'!P3": ""},
+		},
+		{
+			name:       "no result columns",
+			headers:    testHeaders[:headerIndex("Import Status")],
+			cells:      map[string]string{"Supplier ID": "1"},
+			supplierID: 1,
+			want:       map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := tt.headers
+			if headers == nil {
+				headers = testHeaders
+			}
+			row := newTestRow(t, headers, tt.cells)
+			opts := Options{Sheet: tt.sheet}
+			sheet := &MemorySource{}
+
+			start := time.Now().Truncate(time.Second)
+			if err := writeResult(sheet, opts, row, tt.supplierID, tt.err); err != nil {
+				t.Fatalf("writeResult() error = %v", err)
+			}
+
+			timestampCell, _ := row.cellRange(opts, colImportTimestamp)
+			if len(tt.want) > 0 {
+				timestamp, err := time.ParseInLocation(time.DateTime, fmt.Sprint(sheet.Written[timestampCell]), time.Local)
+				if err != nil || timestamp.Before(start) || timestamp.After(time.Now()) {
+					t.Errorf("timestamp %v = %v, want the time of the write", timestampCell, sheet.Written[timestampCell])
+				}
+				delete(sheet.Written, timestampCell)
+			}
+
+			if len(sheet.Written) != len(tt.want) {
+				t.Fatalf("written = %v, want %v", sheet.Written, tt.want)
+			}
+			for cell, value := range tt.want {
+				if got, ok := sheet.Written[cell]; !ok || got != value {
+					t.Errorf("written %s = %#v, want %#v", cell, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteResultError(t *testing.T) {
+	row := newTestRow(t, testHeaders, map[string]string{"Supplier ID": "1"})
+	err := writeResult(failingWriter{}, Options{}, row, 1, nil)
+	if err == nil || !strings.Contains(err.Error(), "[N3 O3 P3]") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("writeResult() error = %v, want the cells and the cause", err)
+	}
+}
+
+// TestImportRowsWriteBack checks the cells ImportRows writes back to the sheet, and that a dry run writes none
+func TestImportRowsWriteBack(t *testing.T) {
+	rows := []map[string]string{
+		{"Supplier ID": "1", "Company Name": "Acme Toys"},
+		{"Company Name": "Bolt", "Entity": "Bolt Co", "Country": "China", "Contact Person": "Wang Fang",
+			"Contact Number": "+86 456", "Origin Source": "Canton Fair"},
+		{"Supplier ID": "99", "Company Name": "Nobody"},
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			dbInstance := newTestDB(t)
+			sheet := newTestSheet(rows...)
+			opts := testOptions(t)
+			opts.DryRun = dryRun
+			if err := ImportRows(dbInstance, sheet, opts); err == nil {
+				t.Fatal("ImportRows() error = nil, want row 5 not imported")
+			}
+
+			if dryRun {
+				if len(sheet.Written) > 0 {
+					t.Errorf("written = %v, want nothing on a dry run", sheet.Written)
+				}
+				return
+			}
+
+			want := map[string]any{
+				testCell("Import Status", 3): report.StatusOK,
+				testCell("Import Error", 3):  "",
+				testCell("Supplier ID", 4):   int64(2),
+				testCell("Import Status", 4): report.StatusOK,
+				testCell("Import Error", 4):  "",
+				testCell("Import Status", 5): report.StatusFailed,
+				testCell("Import Error", 5):  "db: supplier not found: id 99",
+			}
+			for cell, value := range want {
+				if got := sheet.Written[cell]; got != value {
+					t.Errorf("written %s = %#v, want %#v", cell, got, value)
+				}
+			}
+			for row := 3; row <= 5; row++ {
+				if _, ok := sheet.Written[testCell("Import Timestamp", row)]; !ok {
+					t.Errorf("no timestamp written on row %d", row)
+				}
+			}
+		})
+	}
+}
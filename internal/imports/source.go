@@ -0,0 +1,123 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RowSource reads the rows of a run, readRange is the A1 notation of the cells to read, optionally prefixed with
+// the quoted sheet name
+type RowSource interface {
+	ReadRows(readRange string) ([][]string, error)
+}
+
+// CellWriter is implemented by the row sources the import result can be written back to
+type CellWriter interface {
+	// WriteCells writes the values, keyed by A1 range, in a single request
+	WriteCells(values map[string]any) error
+}
+
+// RowWriter is implemented by the row sources an export can be written to
+type RowWriter interface {
+	// WriteRows replaces the cells of the A1 range with the rows, the cells of the range left over are cleared
+	WriteRows(writeRange string, rows [][]string) error
+}
+
+// newSource returns the RowSource of the run source
+func newSource(opts Options) (RowSource, error) {
+	switch opts.Source {
+	case "", SourceGSheet:
+		if opts.SpreadsheetID == "" {
+			return nil, errors.New("spreadsheet ID is empty")
+		}
+		return newGSheetSource(opts.SpreadsheetID)
+	case SourceCSV:
+		return newCSVSource(opts)
+	case SourceXLSX:
+		return newXLSXSource(opts)
+	default:
+		return nil, fmt.Errorf("unknown source %q, expected %s, %s or %s", opts.Source, SourceGSheet, SourceCSV, SourceXLSX)
+	}
+}
+
+// MemorySource is a RowSource holding its rows in memory, the cells written to it are kept in Written and
+// the rows written to it replace its Rows
+type MemorySource struct {
+	// Rows are the rows of the sheet starting from row 1 and column A
+	Rows    [][]string
+	Written map[string]any
+}
+
+func (s *MemorySource) ReadRows(readRange string) ([][]string, error) {
+	return sliceRange(s.Rows, readRange)
+}
+
+func (s *MemorySource) WriteCells(values map[string]any) error {
+	if s.Written == nil {
+		s.Written = map[string]any{}
+	}
+
+	for rng, value := range values {
+		s.Written[rng] = value
+	}
+
+	return nil
+}
+
+func (s *MemorySource) WriteRows(writeRange string, rows [][]string) error {
+	if i := strings.LastIndex(writeRange, "!"); i >= 0 {
+		writeRange = writeRange[i+1:]
+	}
+
+	rng, err := parseA1Range(writeRange)
+	if err != nil {
+		return err
+	}
+
+	endRow := rng.EndRow
+	if endRow == 0 {
+		endRow = len(s.Rows)
+		if last := rng.StartRow - 1 + len(rows); last > endRow {
+			endRow = last
+		}
+	} else if rng.StartRow-1+len(rows) > endRow {
+		return fmt.Errorf("%d rows do not fit in %s", len(rows), writeRange)
+	}
+
+	for len(s.Rows) < rng.StartRow-1+len(rows) {
+		s.Rows = append(s.Rows, []string{})
+	}
+
+	for idx := rng.StartRow - 1; idx < endRow && idx < len(s.Rows); idx++ {
+		var values []string
+		if i := idx - rng.StartRow + 1; i < len(rows) {
+			values = rows[i]
+		}
+
+		endCol := rng.EndCol
+		if endCol < 0 {
+			endCol = len(s.Rows[idx]) - 1
+		}
+		if last := rng.StartCol + len(values) - 1; last > endCol {
+			endCol = last
+		}
+
+		for c := rng.StartCol; c <= endCol; c++ {
+			cell := ""
+			if c-rng.StartCol < len(values) {
+				cell = values[c-rng.StartCol]
+			}
+
+			if c >= len(s.Rows[idx]) {
+				if cell == "" {
+					continue
+				}
+				s.Rows[idx] = append(s.Rows[idx], make([]string, c+1-len(s.Rows[idx]))...)
+			}
+			s.Rows[idx][c] = cell
+		}
+	}
+
+	return nil
+}
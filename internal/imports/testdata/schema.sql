@@ -0,0 +1,106 @@
+-- SQLite version of the tables the importer reads and writes, for the tests
+CREATE TABLE suppliers (
+    id                           INTEGER PRIMARY KEY AUTOINCREMENT,
+    company_name                 TEXT    NOT NULL DEFAULT '',
+    alternate_company_name       TEXT,
+    country                      TEXT    NOT NULL DEFAULT '',
+    city                         TEXT,
+    entity                       TEXT    NOT NULL DEFAULT '',
+    location_region              TEXT,
+    contact_number               TEXT    NOT NULL DEFAULT '',
+    legal_person                 TEXT,
+    contact_person               TEXT    NOT NULL DEFAULT '',
+    social_network_id            TEXT,
+    social_network_type          TEXT,
+    ranking                      TEXT,
+    passed_vetting               TEXT,
+    vetting_info_url             TEXT,
+    classification_id            INTEGER,
+    number_of_employees_range_id INTEGER,
+    status                       TEXT,
+    legal_person_id              TEXT,
+    is_legacy                    BOOLEAN NOT NULL DEFAULT 0,
+    created_at                   DATETIME,
+    created_by                   TEXT,
+    updated_at                   DATETIME,
+    updated_by                   TEXT,
+    deleted_at                   DATETIME,
+    deleted_by                   TEXT
+);
+
+CREATE TABLE supplier_details (
+    id                           INTEGER PRIMARY KEY AUTOINCREMENT,
+    supplier_id                  INTEGER NOT NULL,
+    business_registration_number TEXT,
+    paid_up_capital_in_rmb       INTEGER,
+    registered_business_address  TEXT,
+    supplier_address             TEXT,
+    date_of_establishment        DATETIME,
+    email_address                TEXT,
+    supplier_website_url         TEXT,
+    supplier_type                TEXT,
+    branded_goods                INTEGER NOT NULL DEFAULT 0,
+    brand_check_id               TEXT,
+    origin_source                TEXT    NOT NULL DEFAULT '',
+    gmv_in_rmb                   INTEGER,
+    margin_in_percentage         INTEGER,
+    last_transaction_date        DATETIME,
+    supplier_tier_id             INTEGER,
+    license_to_produce           BOOLEAN,
+    oem_acceptance               BOOLEAN,
+    factory_production_line      BOOLEAN,
+    honest_civil_debtor          BOOLEAN,
+    invoice_under_ninja          BOOLEAN,
+    created_at                   DATETIME,
+    updated_at                   DATETIME,
+    deleted_at                   DATETIME
+);
+
+CREATE TABLE bank_account_details (
+    id                       INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_type             TEXT,
+    account_holder_name      TEXT,
+    account_number           TEXT,
+    bank_name                TEXT,
+    swift_code               TEXT,
+    bank_address             TEXT,
+    supplier_id              INTEGER NOT NULL,
+    supplier_company_address TEXT,
+    created_at               DATETIME,
+    updated_at               DATETIME,
+    deleted_at               DATETIME
+);
+
+CREATE TABLE categories (
+    category_id INTEGER PRIMARY KEY,
+    name        TEXT NOT NULL,
+    parent_id   INTEGER,
+    deleted_at  DATETIME
+);
+
+CREATE TABLE supplier_categories (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    supplier_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    created_at  DATETIME,
+    deleted_at  DATETIME
+);
+
+CREATE TABLE supplier_tiers (
+    id         INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+
+CREATE TABLE supplier_classifications (
+    id         INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE TABLE number_of_employees_ranges (
+    id   INTEGER PRIMARY KEY,
+    name TEXT NOT NULL
+);
@@ -0,0 +1,56 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/lk153/import-gsheet/internal/models"
+	internalValidator "github.com/lk153/import-gsheet/internal/validator"
+)
+
+// validateBean checks the columns set on the bean against the validate tags of its model.
+// A new record (insert) is checked as a whole since the database gets all of its fields.
+func validateBean(table string, bean any, columns []string, insert bool) error {
+	var err error
+	if insert {
+		err = models.Validate(bean)
+	} else {
+		err = models.ValidateFields(bean, columns...)
+	}
+
+	return validationError(table, err)
+}
+
+// tagValues lists the accepted values of the custom validators checking a field against a fixed list
+var tagValues = map[string][]string{
+	"customDeleteReason": internalValidator.DeleteChangeReasons,
+}
+
+// validationError rewrites the validator errors as "table.db_field: reason" messages
+func validationError(table string, err error) error {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	errs := make([]error, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		reason := fe.Tag()
+		if fe.Param() != "" {
+			reason += "=" + fe.Param()
+		}
+		if values, ok := tagValues[fe.Tag()]; ok {
+			reason += ", expected one of: " + strings.Join(values, ", ")
+		}
+		if fe.Value() == nil {
+			errs = append(errs, fmt.Errorf("%s.%s: empty value failed on %s", table, fe.Field(), reason))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s.%s: value %q failed on %s", table, fe.Field(), fmt.Sprint(fe.Value()), reason))
+	}
+
+	return errors.Join(errs...)
+}
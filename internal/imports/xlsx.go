@@ -0,0 +1,145 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newXLSXSource reads the rows of the opts.Sheet sheet of the workbook, or of its first sheet when empty
+func newXLSXSource(opts Options) (RowSource, error) {
+	if opts.File == "" {
+		return nil, errors.New("XLSX file is empty")
+	}
+
+	f, err := excelize.OpenFile(opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("open XLSX file: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	sheet := opts.Sheet
+	switch {
+	case len(sheets) == 0:
+		return nil, fmt.Errorf("workbook %s has no sheet", opts.File)
+	case sheet == "":
+		sheet = sheets[0]
+	case !containsString(sheets, sheet):
+		return nil, fmt.Errorf("workbook %s has no sheet %q, expected one of: %s", opts.File, sheet, strings.Join(sheets, ", "))
+	}
+
+	props, err := f.GetWorkbookProps()
+	if err != nil {
+		return nil, fmt.Errorf("read XLSX file %s: %w", opts.File, err)
+	}
+	date1904 := props.Date1904 != nil && *props.Date1904
+
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("read XLSX file %s: %w", opts.File, err)
+	}
+
+	for r, row := range rows {
+		for c, value := range row {
+			if value == "" {
+				continue
+			}
+
+			cell, _ := excelize.CoordinatesToCellName(c+1, r+1)
+			if row[c], err = xlsxCellValue(f, sheet, cell, value, date1904); err != nil {
+				return nil, fmt.Errorf("read XLSX cell %s!%s: %w", sheet, cell, err)
+			}
+		}
+	}
+
+	return fileSource{records: rows}, nil
+}
+
+// xlsxCellValue converts the raw value of a cell to the text the parsers expect: dates as 2006-01-02
+// (with the time when it has one), percentages as 12.5%, booleans as TRUE/FALSE and numbers without their format
+func xlsxCellValue(f *excelize.File, sheet, cell, value string, date1904 bool) (string, error) {
+	cellType, err := f.GetCellType(sheet, cell)
+	if err != nil {
+		return "", err
+	}
+
+	switch cellType {
+	case excelize.CellTypeBool:
+		if value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case excelize.CellTypeUnset, excelize.CellTypeNumber:
+	default:
+		return value, nil
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value, nil
+	}
+
+	styleID, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		return "", err
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case isDateNumFmt(style):
+		t, err := excelize.ExcelDateToTime(n, date1904)
+		if err != nil {
+			return "", err
+		}
+		if n == math.Trunc(n) {
+			return t.Format(time.DateOnly), nil
+		}
+		return t.Format(time.DateTime), nil
+	case isPercentNumFmt(style):
+		return strconv.FormatFloat(n*100, 'f', -1, 64) + "%", nil
+	default:
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	}
+}
+
+// numFmtLiterals are the parts of a number format that are printed as is: quoted text, escaped characters and
+// the [Red] or [$-409] sections
+var numFmtLiterals = regexp.MustCompile(`"[^"]*"|\\.|\[[^\]]*\]`)
+
+func isDateNumFmt(style *excelize.Style) bool {
+	if style.CustomNumFmt == nil {
+		id := style.NumFmt
+		return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+	}
+
+	code := strings.ToLower(numFmtLiterals.ReplaceAllString(*style.CustomNumFmt, ""))
+	return code != "general" && strings.ContainsAny(code, "ymdhs")
+}
+
+func isPercentNumFmt(style *excelize.Style) bool {
+	if style.CustomNumFmt == nil {
+		return style.NumFmt == 9 || style.NumFmt == 10
+	}
+
+	return strings.Contains(numFmtLiterals.ReplaceAllString(*style.CustomNumFmt, ""), "%")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
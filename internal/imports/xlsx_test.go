@@ -0,0 +1,116 @@
+package imports
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxTestCell is a cell of the test workbook: its value and number format, built-in or custom
+type xlsxTestCell struct {
+	value     any
+	numFmt    int
+	customFmt string
+}
+
+// newTestWorkbook saves a workbook with the cells on the first row of its Suppliers sheet and returns its path
+func newTestWorkbook(t *testing.T, date1904 bool, cells ...xlsxTestCell) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", "Suppliers"); err != nil {
+		t.Fatal(err)
+	}
+	if date1904 {
+		if err := f.SetWorkbookProps(&excelize.WorkbookPropsOptions{Date1904: &date1904}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for c, cell := range cells {
+		name, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue("Suppliers", name, cell.value); err != nil {
+			t.Fatal(err)
+		}
+
+		if cell.numFmt == 0 && cell.customFmt == "" {
+			continue
+		}
+		style := &excelize.Style{NumFmt: cell.numFmt}
+		if cell.customFmt != "" {
+			style.CustomNumFmt = &cell.customFmt
+		}
+		styleID, err := f.NewStyle(style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = f.SetCellStyle("Suppliers", name, name, styleID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "suppliers.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestXLSXSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		cell     xlsxTestCell
+		date1904 bool
+		want     string
+	}{
+		{name: "text", cell: xlsxTestCell{value: "Acme"}, want: "Acme"},
+		{name: "number", cell: xlsxTestCell{value: 1234.5}, want: "1234.5"},
+		{name: "formatted number", cell: xlsxTestCell{value: 1234.5, numFmt: 4}, want: "1234.5"},
+		{name: "date", cell: xlsxTestCell{value: 42070, numFmt: 14}, want: "2015-03-07"},
+		{name: "date and time", cell: xlsxTestCell{value: 42070.5, numFmt: 22}, want: "2015-03-07 12:00:00"},
+		{name: "custom date", cell: xlsxTestCell{value: 42070, customFmt: `yyyy"年"m"月"d"日"`}, want: "2015-03-07"},
+		{name: "1904 date", cell: xlsxTestCell{value: 40608, numFmt: 14}, date1904: true, want: "2015-03-07"},
+		{name: "percent", cell: xlsxTestCell{value: 0.125, numFmt: 10}, want: "12.5%"},
+		{name: "custom percent", cell: xlsxTestCell{value: 0.125, customFmt: "0.0%"}, want: "12.5%"},
+		{name: "true", cell: xlsxTestCell{value: true}, want: "TRUE"},
+		{name: "false", cell: xlsxTestCell{value: false}, want: "FALSE"},
+		// the letters of the color and of the quoted text do not make a date format
+		{name: "colored number", cell: xlsxTestCell{value: 3.5, customFmt: "[Red]0.00"}, want: "3.5"},
+		{name: "number with text", cell: xlsxTestCell{value: 3, customFmt: `0 "days"`}, want: "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := newTestWorkbook(t, tt.date1904, xlsxTestCell{value: "first"}, tt.cell)
+			src, err := newXLSXSource(Options{File: path})
+			if err != nil {
+				t.Fatalf("newXLSXSource() error = %v", err)
+			}
+
+			rows, err := src.ReadRows("A1:B1")
+			if err != nil {
+				t.Fatalf("ReadRows() error = %v", err)
+			}
+			if len(rows) != 1 || len(rows[0]) != 2 || rows[0][1] != tt.want {
+				t.Errorf("rows = %q, want [[first %s]]", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLSXSourceSheet(t *testing.T) {
+	path := newTestWorkbook(t, false, xlsxTestCell{value: "Acme"})
+	if _, err := newXLSXSource(Options{File: path, Sheet: "Suppliers"}); err != nil {
+		t.Fatalf("newXLSXSource() error = %v", err)
+	}
+
+	_, err := newXLSXSource(Options{File: path, Sheet: "To Update on DB"})
+	if err == nil || !strings.Contains(err.Error(), "expected one of: Suppliers") {
+		t.Fatalf("newXLSXSource() error = %v, want the sheets of the workbook", err)
+	}
+}
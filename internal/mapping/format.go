@@ -0,0 +1,98 @@
+package mapping
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// wallClock compares the dates without their location, the sheet has none
+const wallClock = "2006-01-02 15:04:05.999999999"
+
+// Format renders the column field of the bean, a pointer to the table model, as the cell Parse reads back to the
+// same value. NULL fields are blank.
+func (c Column) Format(bean any) (string, error) {
+	value := mapper.FieldByName(reflect.ValueOf(bean).Elem(), c.Field).Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", c.Table, c.Field, err)
+		}
+		value = v
+	}
+
+	if value == nil {
+		return "", nil
+	}
+
+	switch c.Type {
+	case TypeEnum, TypeLookup, TypeRange:
+		return c.formatEnum(value)
+	case TypeDate:
+		t, ok := value.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("%s.%s: cannot format %T as a date", c.Table, c.Field, value)
+		}
+		return formatDate(t, c.Layouts), nil
+	case TypeBool:
+		if b, ok := value.(bool); ok && b {
+			return "YES", nil
+		}
+		return "NO", nil
+	case TypePercent:
+		return fmt.Sprint(value) + "%", nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// FormatTime renders the time as the cell Parse reads back for a date column
+func (c Column) FormatTime(t time.Time) string {
+	return formatDate(t, c.Layouts)
+}
+
+// formatEnum returns the label of the value, the first one in order when several labels share it
+func (c Column) formatEnum(value any) (string, error) {
+	if c.isLookup() && c.Values == nil {
+		return "", fmt.Errorf("%s are not loaded", c.Lookup)
+	}
+
+	labels := make([]string, 0, len(c.Values))
+	for label := range c.Values {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		if sameValue(c.Values[label], value) {
+			return label, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s.%s: %v has no label", c.Table, c.Field, value)
+}
+
+// sameValue compares an enum value of the mapping with a field value, integers of any type are equal
+func sameValue(a, b any) bool {
+	ai, aErr := toInt64(a)
+	bi, bErr := toInt64(b)
+	if aErr == nil && bErr == nil {
+		return ai == bi
+	}
+
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// formatDate formats the date with the first layout that keeps all of it, time.DateTime when none does
+func formatDate(t time.Time, layouts []string) string {
+	for _, layout := range layouts {
+		s := t.Format(layout)
+		if p, err := time.Parse(layout, s); err == nil && p.Format(wallClock) == t.Format(wallClock) {
+			return s
+		}
+	}
+
+	return t.Format(time.DateTime)
+}
@@ -0,0 +1,271 @@
+package mapping
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	TableSuppliers          = "suppliers"
+	TableSupplierDetails    = "supplier_details"
+	TableBankAccountDetails = "bank_account_details"
+)
+
+/*Types a cell can be parsed as*/
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	// TypeNumber and TypePercent accept thousands separators and decimals, rounded to an integer
+	TypeNumber  = "number"
+	TypePercent = "percent"
+	// TypeMoney is an RMB amount, it also accepts currency symbols and the 万/亿 units
+	TypeMoney = "money"
+	TypeDate  = "date"
+	TypeBool  = "bool"
+	TypeEnum  = "enum"
+	// TypeLookup is resolved by name against the records of the Lookup table, loaded by the importer
+	TypeLookup = "lookup"
+	// TypeRange is a lookup whose names are numeric ranges, matched by their bounds as well
+	TypeRange = "range"
+)
+
+/*Transforms applied to a cell before it is parsed*/
+const (
+	TransformTrim  = "trim"
+	TransformUpper = "upper"
+	TransformLower = "lower"
+	TransformNone  = "none"
+)
+
+// DefaultNullValue is the cell value that sets a field to NULL when the mapping sets none
+const DefaultNullValue = "<NULL>"
+
+//go:embed default.yaml
+var defaultMapping []byte
+
+// Mapping describes how the sheet columns are written to the model fields
+type Mapping struct {
+	// DateLayouts are the Go time layouts of the date columns without layouts, DefaultDateLayouts when empty
+	DateLayouts []string `yaml:"date_layouts" json:"date_layouts"`
+	// NullValue is the cell value that sets a nullable field to NULL, DefaultNullValue when empty
+	NullValue string   `yaml:"null_value" json:"null_value"`
+	Columns   []Column `yaml:"columns" json:"columns"`
+}
+
+// Column maps a sheet column, found by its header name, to a db field of a table.
+// Columns without a table are not written to a model and are read by the importer itself.
+type Column struct {
+	Key       string         `yaml:"key" json:"key"`
+	Header    string         `yaml:"header" json:"header"`
+	Aliases   []string       `yaml:"aliases" json:"aliases"`
+	Table     string         `yaml:"table" json:"table"`
+	Field     string         `yaml:"field" json:"field"`
+	Type      string         `yaml:"type" json:"type"`
+	Transform string         `yaml:"transform" json:"transform"`
+	Required  bool           `yaml:"required" json:"required"`
+	Values    map[string]any `yaml:"values" json:"values"`
+	Lookup    string         `yaml:"lookup" json:"lookup"`
+	Layouts   []string       `yaml:"layouts" json:"layouts"`
+
+	// nullValue is the NullValue of the mapping
+	nullValue string
+}
+
+// Load reads the mapping file, yaml or json, or the default mapping when path is empty
+func Load(path string) (*Mapping, error) {
+	if path == "" {
+		return Parse(defaultMapping)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mapping file: %w", err)
+	}
+
+	m, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("mapping file %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// Parse decodes a yaml or json mapping, fills in the defaults and checks it against the models
+func Parse(b []byte) (*Mapping, error) {
+	m := &Mapping{}
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+
+	if m.NullValue = strings.TrimSpace(m.NullValue); m.NullValue == "" {
+		m.NullValue = DefaultNullValue
+	}
+
+	for i := range m.Columns {
+		c := &m.Columns[i]
+		c.nullValue = m.NullValue
+		if c.Type == "" {
+			c.Type = TypeString
+		}
+		if c.Transform == "" {
+			c.Transform = TransformTrim
+		}
+		if c.Type == TypeDate && len(c.Layouts) == 0 {
+			c.Layouts = m.DateLayouts
+			if len(c.Layouts) == 0 {
+				c.Layouts = DefaultDateLayouts
+			}
+		}
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Column returns the column with the given key
+func (m *Mapping) Column(key string) (Column, bool) {
+	for _, c := range m.Columns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+
+	return Column{}, false
+}
+
+// TableColumns returns the columns written to the table, in mapping order
+func (m *Mapping) TableColumns(table string) (columns []Column) {
+	for _, c := range m.Columns {
+		if c.Table == table {
+			columns = append(columns, c)
+		}
+	}
+
+	return
+}
+
+// Lookups returns the lookup tables of the lookup columns
+func (m *Mapping) Lookups() (lookups []string) {
+	seen := map[string]bool{}
+	for _, c := range m.Columns {
+		if c.isLookup() && !seen[c.Lookup] {
+			seen[c.Lookup] = true
+			lookups = append(lookups, c.Lookup)
+		}
+	}
+
+	return
+}
+
+// SetLookup sets the values, name to ID, of the columns resolved against the lookup table
+func (m *Mapping) SetLookup(lookup string, values map[string]any) {
+	for i := range m.Columns {
+		if m.Columns[i].isLookup() && m.Columns[i].Lookup == lookup {
+			m.Columns[i].Values = values
+		}
+	}
+}
+
+// isLookup reports whether the values of the column are loaded from its lookup table
+func (c Column) isLookup() bool {
+	return c.Type == TypeLookup || c.Type == TypeRange
+}
+
+func (m *Mapping) validate() error {
+	if len(m.Columns) == 0 {
+		return errors.New("no columns defined")
+	}
+
+	var errs []error
+	keys := map[string]bool{}
+	fields := map[string]string{}
+	names := map[string]string{}
+	for _, c := range m.Columns {
+		if c.Key == "" {
+			errs = append(errs, fmt.Errorf("column %q: key is empty", c.Header))
+			continue
+		}
+		if keys[c.Key] {
+			errs = append(errs, fmt.Errorf("column %s: duplicated key", c.Key))
+		}
+		keys[c.Key] = true
+
+		if c.Header == "" {
+			errs = append(errs, fmt.Errorf("column %s: header is empty", c.Key))
+		}
+
+		// the header cells are matched by normalized name, a name must point to a single column
+		for _, name := range append([]string{c.Key, c.Header}, c.Aliases...) {
+			normalized := NormalizeName(name)
+			if normalized == "" {
+				errs = append(errs, fmt.Errorf("column %s: name %q has no letter or digit", c.Key, name))
+				continue
+			}
+			if prev, ok := names[normalized]; ok && prev != c.Key {
+				errs = append(errs, fmt.Errorf("column %s: name %q is already used by column %s", c.Key, name, prev))
+				continue
+			}
+			names[normalized] = c.Key
+		}
+
+		switch c.Transform {
+		case TransformTrim, TransformUpper, TransformLower, TransformNone:
+		default:
+			errs = append(errs, fmt.Errorf("column %s: unknown transform %q", c.Key, c.Transform))
+		}
+
+		if len(c.Layouts) > 0 && c.Type != TypeDate {
+			errs = append(errs, fmt.Errorf("column %s: layouts are only used by %s columns", c.Key, TypeDate))
+		}
+
+		if c.Table == "" {
+			continue
+		}
+
+		target := c.Table + "." + c.Field
+		if prev, ok := fields[target]; ok {
+			errs = append(errs, fmt.Errorf("column %s: %s is already mapped by column %s", c.Key, target, prev))
+		}
+		fields[target] = c.Key
+
+		if err := checkField(c); err != nil {
+			errs = append(errs, fmt.Errorf("column %s: %w", c.Key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NormalizeName lower-cases the name and drops everything but letters and digits,
+// so "Paid-up capital (RMB)" and "paid_up_capital_rmb" are the same header
+func NormalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// transform applies the column transform to the cell
+func (c Column) transform(cell string) string {
+	switch c.Transform {
+	case TransformUpper:
+		return strings.ToUpper(strings.TrimSpace(cell))
+	case TransformLower:
+		return strings.ToLower(strings.TrimSpace(cell))
+	case TransformNone:
+		return cell
+	default:
+		return strings.TrimSpace(cell)
+	}
+}
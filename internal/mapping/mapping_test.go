@@ -0,0 +1,67 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDefault(t *testing.T) {
+	if _, err := Load(""); err != nil {
+		t.Fatalf("default mapping: %v", err)
+	}
+}
+
+func TestParseNameCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns string
+		wantErr string
+	}{
+		{
+			name: "distinct names",
+			columns: `
+  - {key: supplier_id, header: Supplier ID, aliases: [ID]}
+  - {key: company_name, header: Company Name, table: suppliers, field: company_name, aliases: [Supplier Name]}`,
+		},
+		{
+			name: "alias of the same column",
+			columns: `
+  - {key: company_name, header: Company Name, table: suppliers, field: company_name, aliases: [company-name]}`,
+		},
+		{
+			name: "header of another column",
+			columns: `
+  - {key: supplier_id, header: Supplier ID}
+  - {key: company_name, header: Supplier-ID, table: suppliers, field: company_name}`,
+			wantErr: `column company_name: name "Supplier-ID" is already used by column supplier_id`,
+		},
+		{
+			name: "alias of another column",
+			columns: `
+  - {key: supplier_id, header: Supplier ID, aliases: [Name]}
+  - {key: company_name, header: Company Name, table: suppliers, field: company_name, aliases: [name]}`,
+			wantErr: `column company_name: name "name" is already used by column supplier_id`,
+		},
+		{
+			name: "name without letters",
+			columns: `
+  - {key: supplier_id, header: Supplier ID, aliases: ["#"]}`,
+			wantErr: `column supplier_id: name "#" has no letter or digit`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte("columns:" + tt.columns))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package mapping
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+
+	"github.com/lk153/import-gsheet/internal/models"
+)
+
+// mapper resolves the db tags the same way sqlx does
+var mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+var tableModels = map[string]reflect.Type{
+	TableSuppliers:          reflect.TypeOf(models.Supplier{}),
+	TableSupplierDetails:    reflect.TypeOf(models.SupplierDetail{}),
+	TableBankAccountDetails: reflect.TypeOf(models.BankAccountDetails{}),
+}
+
+var intFieldTypes = []reflect.Type{reflect.TypeOf(int64(0)), reflect.TypeOf(int16(0)), reflect.TypeOf(sql.NullInt64{})}
+
+// fieldTypes lists the model field types each column type can be written to
+var fieldTypes = map[string][]reflect.Type{
+	TypeString:  {reflect.TypeOf(""), reflect.TypeOf(sql.NullString{})},
+	TypeInt:     intFieldTypes,
+	TypeNumber:  intFieldTypes,
+	TypePercent: intFieldTypes,
+	TypeMoney:   intFieldTypes,
+	TypeDate:    {reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{})},
+	TypeBool:    {reflect.TypeOf(false), reflect.TypeOf(sql.NullBool{})},
+}
+
+// checkField makes sure the table model has a field with the db tag and that the column type can be written to it
+func checkField(c Column) error {
+	model, ok := tableModels[c.Table]
+	if !ok {
+		return fmt.Errorf("unknown table %q", c.Table)
+	}
+
+	fi, ok := mapper.TypeMap(model).Names[c.Field]
+	if !ok || fi.Embedded || strings.Contains(fi.Path, ".") {
+		return fmt.Errorf("%s has no db field %q", model.Name(), c.Field)
+	}
+
+	if c.Type == TypeEnum {
+		if len(c.Values) == 0 {
+			return fmt.Errorf("enum %s.%s has no values", c.Table, c.Field)
+		}
+
+		for label, value := range c.Values {
+			if err := assign(reflect.New(fi.Field.Type).Elem(), value); err != nil {
+				return fmt.Errorf("enum value %q of %s.%s: %w", label, c.Table, c.Field, err)
+			}
+		}
+		return nil
+	}
+
+	if c.isLookup() && c.Lookup == "" {
+		return fmt.Errorf("%s %s.%s has no lookup table", c.Type, c.Table, c.Field)
+	}
+
+	types, ok := fieldTypes[c.Type]
+	switch c.Type {
+	case TypeLookup:
+		// the ID of a lookup record, or the name itself for the lookups of the values stored in a field
+		types, ok = append(fieldTypes[TypeString], intFieldTypes...), true
+	case TypeRange:
+		types, ok = intFieldTypes, true
+	}
+	if !ok {
+		return fmt.Errorf("unknown type %q", c.Type)
+	}
+
+	for _, t := range types {
+		if t == fi.Field.Type {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("type %s cannot be written to %s.%s of type %s", c.Type, c.Table, c.Field, fi.Field.Type)
+}
+
+// Apply parses the cell and writes it to the column field of the bean, a pointer to the table model.
+// It reports whether the field was set; blank cells leave the bean untouched and the null value sets it to NULL.
+func (c Column) Apply(bean any, cell string) (set bool, err error) {
+	if strings.TrimSpace(cell) == "" {
+		return false, nil
+	}
+
+	if c.nullValue != "" && strings.TrimSpace(cell) == c.nullValue {
+		if !c.Nullable() {
+			return false, fmt.Errorf("%q: %s.%s does not accept NULL", c.Header, c.Table, c.Field)
+		}
+
+		field := mapper.FieldByName(reflect.ValueOf(bean).Elem(), c.Field)
+		field.Set(reflect.Zero(field.Type()))
+		return true, nil
+	}
+
+	value, err := c.Parse(cell)
+	if err != nil {
+		return false, fmt.Errorf("%q: %w", c.Header, err)
+	}
+
+	field := mapper.FieldByName(reflect.ValueOf(bean).Elem(), c.Field)
+	if err = assign(field, value); err != nil {
+		return false, fmt.Errorf("%q: %w", c.Header, err)
+	}
+
+	return true, nil
+}
+
+// Parse converts a non-blank cell to the Go value of the column type
+func (c Column) Parse(cell string) (any, error) {
+	value := c.transform(cell)
+	switch c.Type {
+	case TypeInt:
+		return parseInt(value)
+	case TypeNumber:
+		return parseNumber(value)
+	case TypePercent:
+		return parsePercent(value)
+	case TypeMoney:
+		return parseMoney(value)
+	case TypeDate:
+		return parseDate(value, c.Layouts)
+	case TypeBool:
+		return parseBool(value)
+	case TypeEnum, TypeLookup:
+		return c.parseEnum(value)
+	case TypeRange:
+		return c.parseRangeValue(value)
+	default:
+		return value, nil
+	}
+}
+
+// Rounding describes how the number, percent or money cell is rounded to be stored in the integer field,
+// e.g. "12.5%" stored as 13; it is empty when the cell is stored as is or cannot be parsed
+func (c Column) Rounding(cell string) string {
+	value := c.transform(cell)
+	if strings.TrimSpace(cell) == "" || (c.nullValue != "" && strings.TrimSpace(cell) == c.nullValue) {
+		return ""
+	}
+
+	var f float64
+	var err error
+	switch c.Type {
+	case TypeNumber:
+		f, err = numberValue(value)
+	case TypePercent:
+		f, err = percentValue(value)
+	case TypeMoney:
+		f, err = moneyValue(value)
+	default:
+		return ""
+	}
+	if err != nil || f == math.Round(f) {
+		return ""
+	}
+
+	return fmt.Sprintf("%q: %q stored rounded as %d", c.Header, value, int64(math.Round(f)))
+}
+
+func (c Column) parseEnum(value string) (any, error) {
+	if c.Type == TypeLookup && c.Values == nil {
+		return nil, fmt.Errorf("%s are not loaded", c.Lookup)
+	}
+
+	labels := make([]string, 0, len(c.Values))
+	for label, v := range c.Values {
+		if strings.EqualFold(label, value) {
+			return v, nil
+		}
+		labels = append(labels, label)
+	}
+
+	sort.Strings(labels)
+	return nil, fmt.Errorf("unknown value %q, expected one of: %s", value, strings.Join(labels, ", "))
+}
+
+// assign writes the parsed value to the model field, using sql.Scanner for the sql.Null* types
+func assign(field reflect.Value, value any) error {
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	v := reflect.ValueOf(value)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(i) {
+			return fmt.Errorf("%d is out of range", i)
+		}
+		field.SetInt(i)
+		return nil
+	default:
+		if value == nil {
+			return fmt.Errorf("%s cannot be cleared, it does not accept NULL", field.Type())
+		}
+		if v.Kind() != field.Kind() || !v.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("cannot write %T to %s", value, field.Type())
+		}
+		field.Set(v.Convert(field.Type()))
+		return nil
+	}
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case string:
+		return parseInt(v)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an integer", value)
+	}
+}
+
+// Nullable reports whether the column field accepts NULL, i.e. is a sql.Null* type
+func (c Column) Nullable() bool {
+	model, ok := tableModels[c.Table]
+	if !ok {
+		return false
+	}
+
+	fi, ok := mapper.TypeMap(model).Names[c.Field]
+	if !ok {
+		return false
+	}
+
+	_, ok = reflect.New(fi.Field.Type).Interface().(sql.Scanner)
+	return ok
+}
@@ -0,0 +1,210 @@
+package mapping
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+func parseInt(value string) (int64, error) {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+
+	return i, nil
+}
+
+// parseNumber parses a number with optional thousands separators, e.g. 1,234,000, rounded to an integer
+func parseNumber(value string) (int64, error) {
+	f, err := numberValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return roundValue(value, f)
+}
+
+// numberValue parses a number with optional thousands separators as is
+func numberValue(value string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+
+	return f, nil
+}
+
+// parsePercent parses a percentage with or without the % sign, e.g. 12.5%, rounded to a whole percent
+func parsePercent(value string) (int64, error) {
+	f, err := percentValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return roundValue(value, f)
+}
+
+// percentValue parses a percentage with or without the % sign as is
+func percentValue(value string) (float64, error) {
+	f, err := numberValue(strings.TrimSpace(strings.TrimSuffix(value, "%")))
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", value)
+	}
+
+	return f, nil
+}
+
+// roundValue rounds the parsed value of the cell to the integer stored in the field
+func roundValue(value string, f float64) (int64, error) {
+	f = math.Round(f)
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("value %q is out of range", value)
+	}
+
+	return int64(f), nil
+}
+
+// DefaultDateLayouts are the layouts of the date columns when the mapping sets none, tried in order. They all start
+// with the year: 3/7/2015 is March 7 in an en_US sheet and July 3 in most others, so the day-first and month-first
+// layouts are only used when the mapping lists them.
+var DefaultDateLayouts = []string{
+	time.DateOnly,
+	time.DateTime,
+	"2006/1/2",
+	"2006-1-2",
+	"2006.1.2",
+	"2006年1月2日",
+}
+
+// sheetsEpoch is day 0 of the Google Sheets (and Excel) date serial numbers
+var sheetsEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// minDateSerial and maxDateSerial bound the serial numbers read as dates, 1927-05-18 to 9999-12-31: smaller numbers,
+// such as a year typed alone, are more likely something else than a date
+const (
+	minDateSerial = 10000
+	maxDateSerial = 2958466
+)
+
+// rmbMarks are the currency symbols and codes allowed around an amount, all of them RMB
+var rmbMarks = []string{"RMB", "CNY", "¥", "￥", "元"}
+
+// moneyUnits are the Chinese multipliers an amount can end with, e.g. 500万 or 1.5亿, and combine into compound
+// units such as 十万, 百万 or 千万
+var moneyUnits = map[rune]float64{'十': 10, '百': 1e2, '千': 1e3, '万': 1e4, '亿': 1e8}
+
+// moneyUnitsHelp lists the units in the errors of the amounts that cannot be parsed
+const moneyUnitsHelp = "千, 万, 亿 or a compound unit such as 十万, 百万 or 千万"
+
+// parseMoney parses an RMB amount with optional currency symbol, thousands separators, decimals and Chinese units,
+// e.g. ¥1,000,000, 500万 or 1.5亿 RMB, rounded to an integer
+func parseMoney(value string) (int64, error) {
+	f, err := moneyValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return roundValue(value, f)
+}
+
+// moneyValue parses an RMB amount like parseMoney, as is
+func moneyValue(value string) (float64, error) {
+	amount := strings.TrimSpace(value)
+	for trimmed := ""; trimmed != amount; {
+		trimmed = amount
+		for _, mark := range rmbMarks {
+			amount = strings.TrimSpace(trimPrefixFold(amount, mark))
+			amount = strings.TrimSpace(trimSuffixFold(amount, mark))
+		}
+	}
+
+	multiplier := 1.0
+	for amount != "" {
+		r, size := utf8.DecodeLastRuneInString(amount)
+		m, ok := moneyUnits[r]
+		if !ok {
+			break
+		}
+		amount, multiplier = strings.TrimSpace(amount[:len(amount)-size]), multiplier*m
+	}
+
+	for _, r := range amount {
+		if unicode.Is(unicode.Sc, r) {
+			return 0, fmt.Errorf("invalid amount %q, only RMB amounts are accepted", value)
+		}
+	}
+
+	f, err := strconv.ParseFloat(strings.ReplaceAll(amount, ",", ""), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid amount %q, expected a number optionally followed by %s", value, moneyUnitsHelp)
+	}
+
+	return f * multiplier, nil
+}
+
+func trimPrefixFold(s, prefix string) string {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):]
+	}
+
+	return s
+}
+
+func trimSuffixFold(s, suffix string) string {
+	if len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return s[:len(s)-len(suffix)]
+	}
+
+	return s
+}
+
+// parseDate parses the cell with the first matching layout, or as a serial number when the cell is unformatted
+func parseDate(value string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	if t, ok := parseDateSerial(value); ok {
+		return t, nil
+	}
+
+	if year, err := strconv.Atoi(value); err == nil && year >= 1000 && year <= 9999 {
+		return time.Time{}, fmt.Errorf("invalid date %q, a year alone is not a date: expected one of %s", value, strings.Join(layouts, ", "))
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q, expected one of %s or a serial number", value, strings.Join(layouts, ", "))
+}
+
+// parseDateSerial converts a serial number, the days since 1899-12-30 with the time of day as fraction
+func parseDateSerial(value string) (time.Time, bool) {
+	days, err := strconv.ParseFloat(value, 64)
+	if err != nil || days < minDateSerial || days >= maxDateSerial {
+		return time.Time{}, false
+	}
+
+	return sheetsEpoch.Add(time.Duration(math.Round(days * 24 * float64(time.Hour)))), true
+}
+
+// boolClear is the bool cell value that sets the field to NULL, a blank cell leaves it untouched
+const boolClear = "CLEAR"
+
+// parseBool parses the tri-state bool cells: YES, NO or CLEAR, which returns nil
+func parseBool(value string) (any, error) {
+	switch strings.ToUpper(value) {
+	case "YES", "Y", "TRUE":
+		return true, nil
+	case "NO", "N", "FALSE":
+		return false, nil
+	case boolClear:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid value %q, expected YES, NO or %s", value, boolClear)
+	}
+}
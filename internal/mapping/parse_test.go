@@ -0,0 +1,99 @@
+package mapping
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "1,000,000", want: 1000000},
+		{value: " 100", want: 100},
+		{value: "¥1,234.4", want: 1234},
+		{value: "500万", want: 5000000},
+		{value: "1.5亿 RMB", want: 150000000},
+		{value: "5千万", want: 50000000},
+		{value: "3百万元", want: 3000000},
+		{value: "2.5十万", want: 250000},
+		{value: "2千", want: 2000},
+		{value: "$100", wantErr: true},
+		{value: "万", wantErr: true},
+		{value: "a lot", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseMoney(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMoney(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMoney(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		layouts []string
+		want    string
+		wantErr bool
+	}{
+		{value: "2015-03-07", want: "2015-03-07"},
+		{value: "2015/3/7", want: "2015-03-07"},
+		{value: "2015年3月7日", want: "2015-03-07"},
+		{value: "3/7/2015", wantErr: true},
+		{value: "07-03-2015", wantErr: true},
+		{value: "3/7/2015", layouts: []string{"1/2/2006"}, want: "2015-03-07"},
+		{value: "07-03-2015", layouts: []string{"02-01-2006"}, want: "2015-03-07"},
+		{value: "42070", want: "2015-03-07"},
+		{value: "2015", wantErr: true},
+		{value: "9999", wantErr: true},
+		{value: "120", wantErr: true},
+		{value: "March 7th", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			layouts := tt.layouts
+			if layouts == nil {
+				layouts = DefaultDateLayouts
+			}
+			got, err := parseDate(tt.value, layouts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDate(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && got.Format("2006-01-02") != tt.want {
+				t.Errorf("parseDate(%q) = %s, want %s", tt.value, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnRounding(t *testing.T) {
+	tests := []struct {
+		name string
+		col  Column
+		cell string
+		want string
+	}{
+		{name: "whole percent", col: Column{Header: "Margin", Type: TypePercent}, cell: "12%"},
+		{name: "percent with decimals", col: Column{Header: "Margin", Type: TypePercent}, cell: "12.5%", want: `"Margin": "12.5%" stored rounded as 13`},
+		{name: "number with decimals", col: Column{Header: "Employees", Type: TypeNumber}, cell: "1,234.4", want: `"Employees": "1,234.4" stored rounded as 1234`},
+		{name: "money with cents", col: Column{Header: "GMV", Type: TypeMoney}, cell: "¥10.50", want: `"GMV": "¥10.50" stored rounded as 11`},
+		{name: "money unit", col: Column{Header: "GMV", Type: TypeMoney}, cell: "1.5亿"},
+		{name: "blank", col: Column{Header: "GMV", Type: TypeMoney}, cell: " "},
+		{name: "not a number", col: Column{Header: "Name", Type: TypeString}, cell: "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.col.Rounding(tt.cell); got != tt.want {
+				t.Errorf("Rounding(%q) = %q, want %q", tt.cell, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package mapping
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numRange holds the bounds of a range label such as "50-99" or ">=5000", both included
+type numRange struct {
+	min, max int64
+}
+
+var (
+	rangeBetween = regexp.MustCompile(`^(\d+)\s*(?:-|~|to)\s*(\d+)$`)
+	rangeBelow   = regexp.MustCompile(`^(?:<|less than|fewer than|under|below)\s*(\d+)$`)
+	rangeUpTo    = regexp.MustCompile(`^(?:<=|≤|up to|at most)\s*(\d+)$`)
+	rangeAbove   = regexp.MustCompile(`^(?:>|more than|over|above|greater than)\s*(\d+)$`)
+	rangeFrom    = regexp.MustCompile(`^(?:>=|≥|at least|from)\s*(\d+)$`)
+	rangeOrMore  = regexp.MustCompile(`^(\d+)\s*(?:\+|or more|and above|and more)$`)
+	// rangeUnit is dropped from the labels, e.g. "50-99 employees"
+	rangeUnit = regexp.MustCompile(`\s*(?:employees|people|staff|persons)$`)
+)
+
+// parseRange reads the bounds of a range label, it reports false when the label is not a range
+func parseRange(label string) (r numRange, ok bool) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	label = rangeUnit.ReplaceAllString(strings.ReplaceAll(label, ",", ""), "")
+
+	num := func(s string) int64 {
+		i, _ := strconv.ParseInt(s, 10, 64)
+		return i
+	}
+
+	if m := rangeBetween.FindStringSubmatch(label); m != nil {
+		return numRange{num(m[1]), num(m[2])}, num(m[1]) <= num(m[2])
+	}
+	if m := rangeBelow.FindStringSubmatch(label); m != nil {
+		return numRange{0, num(m[1]) - 1}, num(m[1]) > 0
+	}
+	if m := rangeUpTo.FindStringSubmatch(label); m != nil {
+		return numRange{0, num(m[1])}, true
+	}
+	if m := rangeAbove.FindStringSubmatch(label); m != nil {
+		return numRange{num(m[1]) + 1, math.MaxInt64}, true
+	}
+	if m := rangeFrom.FindStringSubmatch(label); m != nil {
+		return numRange{num(m[1]), math.MaxInt64}, true
+	}
+	if m := rangeOrMore.FindStringSubmatch(label); m != nil {
+		return numRange{num(m[1]), math.MaxInt64}, true
+	}
+
+	return numRange{}, false
+}
+
+// parseRangeValue matches the cell against the range labels of the column: by label, by bounds
+// ("less than 50" matches "<50" and "0-49") or, for a plain number, by the range containing it
+func (c Column) parseRangeValue(value string) (any, error) {
+	if c.Values == nil {
+		return nil, fmt.Errorf("%s are not loaded", c.Lookup)
+	}
+
+	labels := make([]string, 0, len(c.Values))
+	for label, v := range c.Values {
+		if strings.EqualFold(strings.TrimSpace(label), value) {
+			return v, nil
+		}
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	bounds, isRange := parseRange(value)
+	n, err := strconv.ParseInt(strings.ReplaceAll(value, ",", ""), 10, 64)
+	isNumber := err == nil && n >= 0
+	for _, label := range labels {
+		r, ok := parseRange(label)
+		switch {
+		case !ok:
+			continue
+		case isRange && r == bounds, isNumber && r.min <= n && n <= r.max:
+			return c.Values[label], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown value %q, expected one of: %s", value, strings.Join(labels, ", "))
+}
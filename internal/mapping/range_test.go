@@ -0,0 +1,56 @@
+package mapping
+
+import "testing"
+
+func TestParseRangeValue(t *testing.T) {
+	col := Column{Type: TypeRange, Lookup: "number_of_employees_ranges", Values: map[string]any{
+		"<50":      int64(1),
+		"50-99":    int64(2),
+		"100-499":  int64(3),
+		"500-4999": int64(4),
+		">=5000":   int64(5),
+	}}
+
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "<50", want: 1},
+		{value: "less than 50", want: 1},
+		{value: "0-49", want: 1},
+		{value: "0 to 49 employees", want: 1},
+		{value: "49", want: 1},
+		{value: "50", want: 2},
+		{value: "120", want: 3},
+		{value: "1,200", want: 4},
+		{value: ">=5000", want: 5},
+		{value: "5000+", want: 5},
+		{value: "more than 4999", want: 5},
+		{value: "at least 5000", want: 5},
+		{value: "12000", want: 5},
+		{value: "0-50", wantErr: true},
+		{value: "more than 5000", wantErr: true},
+		{value: "-3", wantErr: true},
+		{value: "a few", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := col.parseRangeValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRangeValue(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseRangeValue(%q) = %v, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRangeValueNotLoaded(t *testing.T) {
+	col := Column{Type: TypeRange, Lookup: "number_of_employees_ranges"}
+	if _, err := col.parseRangeValue("120"); err == nil {
+		t.Fatal("parseRangeValue() error = nil, want the ranges not loaded")
+	}
+}
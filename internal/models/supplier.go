@@ -15,11 +15,11 @@ type Supplier struct {
 	ContactNumber            string          `db:"contact_number"`
 	LegalPerson              sql.NullString  `db:"legal_person"`
 	ContactPerson            string          `db:"contact_person"`
-	SocialNetworkId          sql.NullString  `db:"social_network_id"`
+	SocialNetworkId          sql.NullString  `db:"social_network_id" validate:"omitempty,customSocialNetworkId"`
 	SocialNetworkType        sql.NullString  `db:"social_network_type"`
 	Ranking                  sql.NullString  `db:"ranking"`
 	PassedVetting            sql.NullString  `db:"passed_vetting"`
-	VettingInfoUrl           sql.NullString  `db:"vetting_info_url"`
+	VettingInfoUrl           sql.NullString  `db:"vetting_info_url" validate:"omitempty,customUrl"`
 	ClassificationID         sql.NullInt64   `db:"classification_id"`
 	NumberOfEmployeesRangeID sql.NullInt64   `db:"number_of_employees_range_id"`
 	Status                   sql.NullString  `db:"status"`
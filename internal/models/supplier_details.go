@@ -8,18 +8,18 @@ type SupplierDetail struct {
 	Id                         int64            `db:"id"`
 	SupplierId                 int64            `db:"supplier_id"`
 	BusinessRegistrationNumber sql.NullString   `db:"business_registration_number"`
-	PaidUpCapitalRMB           sql.NullInt64    `db:"paid_up_capital_in_rmb"`
+	PaidUpCapitalRMB           sql.NullInt64    `db:"paid_up_capital_in_rmb" validate:"omitempty,min=0"`
 	RegisteredBusinessAddress  sql.NullString   `db:"registered_business_address"`
 	SupplierAddress            sql.NullString   `db:"supplier_address"`
 	DateOfEstablishment        sql.NullTime     `db:"date_of_establishment"`
-	EmailAddress               sql.NullString   `db:"email_address"`
-	SupplierWebsiteURL         sql.NullString   `db:"supplier_website_url"`
+	EmailAddress               sql.NullString   `db:"email_address" validate:"omitempty,customEmail"`
+	SupplierWebsiteURL         sql.NullString   `db:"supplier_website_url" validate:"omitempty,customUrl"`
 	SupplierType               sql.NullString   `db:"supplier_type"`
 	BrandedGoods               int16            `db:"branded_goods"`
 	BrandCheckID               sql.NullString   `db:"brand_check_id"`
 	OriginSource               string           `db:"origin_source"`
-	GMVInRMB                   sql.NullInt64    `db:"gmv_in_rmb"`
-	MarginInPercentage         sql.NullInt64    `db:"margin_in_percentage"`
+	GMVInRMB                   sql.NullInt64    `db:"gmv_in_rmb" validate:"omitempty,min=0"`
+	MarginInPercentage         sql.NullInt64    `db:"margin_in_percentage" validate:"omitempty,min=0,max=100"`
 	LastTransactionDate        sql.NullTime     `db:"last_transaction_date"`
 	SupplierTierId             sql.NullInt64    `db:"supplier_tier_id"`
 	SupplierTierRel            *SupplierTierRel `db:"supplier_tier_rel"`
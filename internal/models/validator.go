@@ -45,3 +45,29 @@ func ValidateValuer(field reflect.Value) interface{} {
 
 	return nil
 }
+
+// Validate checks all the fields of the model against their validate tags
+func Validate(model any) error {
+	return validate.Struct(model)
+}
+
+// ValidateFields checks only the model fields with the given db tags against their validate tags.
+// The field names in the returned validator.ValidationErrors are the db tags as well.
+func ValidateFields(model any, dbFields ...string) error {
+	if len(dbFields) == 0 {
+		return nil
+	}
+
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	names := make([]string, 0, len(dbFields))
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.SplitN(t.Field(i).Tag.Get("db"), ",", 2)[0]
+		for _, dbField := range dbFields {
+			if name == dbField {
+				names = append(names, t.Field(i).Name)
+			}
+		}
+	}
+
+	return validate.StructPartial(model, names...)
+}
@@ -0,0 +1,139 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lk153/import-gsheet/utils"
+)
+
+/*Status of a row compared with the database*/
+const (
+	DiffInSync  = "IN_SYNC"
+	DiffDiffers = "DIFFERS"
+	// DiffMissing is a row whose supplier ID is not in the database, DiffNew a row without supplier ID
+	DiffMissing = "MISSING"
+	DiffNew     = "NEW"
+	// DiffInvalid is a row whose cells cannot be parsed, DiffError one the database could not be read for
+	DiffInvalid = "INVALID"
+	DiffError   = "ERROR"
+)
+
+// FormatText is the default format of the reconciliation
+const FormatText = "text"
+
+// Reconciliation is the comparison of the sheet rows with the stored suppliers
+type Reconciliation struct {
+	Source    string         `json:"source"`
+	CheckedAt time.Time      `json:"checked_at"`
+	Summary   map[string]int `json:"summary"`
+	Rows      []*DiffRow     `json:"rows"`
+}
+
+// DiffRow is the comparison of a single sheet row
+type DiffRow struct {
+	Row        int         `json:"row"`
+	SupplierID int64       `json:"supplier_id,omitempty"`
+	Status     string      `json:"status"`
+	Fields     []FieldDiff `json:"fields,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// FieldDiff is a field whose sheet value differs from the stored one
+type FieldDiff struct {
+	Table  string `json:"table"`
+	Field  string `json:"field"`
+	Sheet  string `json:"sheet"`
+	Stored string `json:"stored"`
+}
+
+func NewReconciliation(source string) *Reconciliation {
+	return &Reconciliation{Source: source, CheckedAt: time.Now(), Summary: map[string]int{}}
+}
+
+// NewRow adds the sheet row to the reconciliation and returns it to be filled in
+func (r *Reconciliation) NewRow(number int) *DiffRow {
+	row := &DiffRow{Row: number}
+	r.Rows = append(r.Rows, row)
+	return row
+}
+
+// Finish counts the rows by status
+func (r *Reconciliation) Finish() {
+	r.Summary = map[string]int{}
+	for _, row := range r.Rows {
+		r.Summary[row.Status]++
+	}
+}
+
+// DiffFormat returns the format a reconciliation is written to the file in: format, or when empty FormatJSON for
+// a .json file and FormatText for any other
+func DiffFormat(path, format string) (string, error) {
+	switch format {
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			return FormatJSON, nil
+		}
+		return FormatText, nil
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+}
+
+// Write writes the reconciliation in the format, FormatText when empty. The text is colored when colored is set,
+// for a terminal.
+func (r *Reconciliation) Write(w io.Writer, format string, colored bool) error {
+	switch format {
+	case "", FormatText:
+		return r.WriteText(w, colored)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	default:
+		return fmt.Errorf("unknown format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+}
+
+// WriteText writes the rows that are not in sync, followed by the totals, in color when colored is set
+func (r *Reconciliation) WriteText(w io.Writer, colored bool) error {
+	warn, fatal, info := utils.Warn, utils.Fatal, utils.Info
+	if !colored {
+		warn, fatal, info = fmt.Sprint, fmt.Sprint, fmt.Sprint
+	}
+
+	for _, row := range r.Rows {
+		if row.Status == DiffInSync {
+			continue
+		}
+
+		color := warn
+		if row.Status == DiffInvalid || row.Status == DiffMissing || row.Status == DiffError {
+			color = fatal
+		}
+
+		supplier := "-"
+		if row.SupplierID != 0 {
+			supplier = fmt.Sprint(row.SupplierID)
+		}
+
+		fmt.Fprintf(w, "%s row %d supplier %s\n", color(row.Status), row.Row, supplier)
+		for _, field := range row.Fields {
+			fmt.Fprintf(w, "  %s.%s: sheet %q, stored %q\n", field.Table, field.Field, field.Sheet, field.Stored)
+		}
+		if row.Error != "" {
+			fmt.Fprintf(w, "  error %s\n", strings.ReplaceAll(row.Error, "\n", "\n    "))
+		}
+	}
+
+	fmt.Fprintln(w, info("Reconciliation of ", r.Source))
+	_, err := fmt.Fprintf(w, "  rows: %d, in sync: %d, differ: %d, missing: %d, new: %d, invalid: %d, errors: %d\n", len(r.Rows),
+		r.Summary[DiffInSync], r.Summary[DiffDiffers], r.Summary[DiffMissing], r.Summary[DiffNew], r.Summary[DiffInvalid], r.Summary[DiffError])
+	return err
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffFormat(t *testing.T) {
+	tests := []struct {
+		path    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{path: "", want: FormatText},
+		{path: "diff.json", want: FormatJSON},
+		{path: "DIFF.JSON", want: FormatJSON},
+		{path: "diff.txt", want: FormatText},
+		{path: "diff", want: FormatText},
+		{path: "diff.txt", format: FormatJSON, want: FormatJSON},
+		{path: "diff.json", format: FormatText, want: FormatText},
+		{path: "diff.txt", format: "txt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.format, func(t *testing.T) {
+			got, err := DiffFormat(tt.path, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiffFormat() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DiffFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciliationWriteText(t *testing.T) {
+	r := NewReconciliation("'To Update on DB'!A3:BA")
+	r.NewRow(3).Status = DiffInSync
+	row := r.NewRow(4)
+	row.Status, row.SupplierID = DiffDiffers, 7
+	row.Fields = []FieldDiff{{Table: "suppliers", Field: "company_name", Sheet: "Acme Toys", Stored: "Acme"}}
+	r.NewRow(5).Status = DiffMissing
+	r.Finish()
+
+	for _, colored := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := r.Write(&buf, "", colored); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		out := buf.String()
+		if strings.Contains(out, "\033[") != colored {
+			t.Errorf("colored %v, output %q", colored, out)
+		}
+		if colored {
+			continue
+		}
+
+		want := `DIFFERS row 4 supplier 7
+  suppliers.company_name: sheet "Acme Toys", stored "Acme"
+MISSING row 5 supplier -
+Reconciliation of 'To Update on DB'!A3:BA
+  rows: 3, in sync: 1, differ: 1, missing: 1, new: 0, invalid: 0, errors: 0
+`
+		if out != want {
+			t.Errorf("output = %q, want %q", out, want)
+		}
+	}
+}
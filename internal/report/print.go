@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lk153/import-gsheet/utils"
+)
+
+// Print writes a one-line outcome of the row, followed by its changes and errors
+func (row *Row) Print(w io.Writer) {
+	color := utils.Info
+	switch row.Status {
+	case StatusFailed:
+		color = utils.Fatal
+	case StatusSkipped:
+		color = utils.Warn
+	}
+
+	actions := make([]string, 0, len(row.Actions))
+	for _, action := range row.Actions {
+		actions = append(actions, fmt.Sprintf("%s:%s(%d)", action.Table, action.Action, action.RowsAffected))
+	}
+
+	supplier := "-"
+	if row.SupplierID != 0 {
+		supplier = fmt.Sprint(row.SupplierID)
+	}
+
+	fmt.Fprintf(w, "%s row %d supplier %s %s\n", color(row.Status), row.Row, supplier, strings.Join(actions, " "))
+	if row.DeleteReason != "" {
+		fmt.Fprintf(w, "  deleted by %s: %s\n", row.DeletedBy, row.DeleteReason)
+	}
+	if len(row.Conflicts) > 0 && row.Status == StatusOK {
+		fmt.Fprintf(w, "  %s %s\n", utils.Warn("conflict overridden:"), strings.Join(row.Conflicts, ", "))
+	}
+	for _, warning := range row.Warnings {
+		fmt.Fprintf(w, "  %s %s\n", utils.Warn("warning:"), warning)
+	}
+	for _, change := range row.Changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+	for _, e := range row.Errors {
+		fmt.Fprintf(w, "  error %s\n", strings.ReplaceAll(e.String(), "\n", "\n    "))
+	}
+}
+
+// PrintSummary writes the totals of the run
+func (r *Report) PrintSummary(w io.Writer) {
+	title := "Import summary"
+	if r.DryRun {
+		title += " (dry run, nothing committed)"
+	}
+
+	fmt.Fprintln(w, utils.Info(title))
+	fmt.Fprintf(w, "  rows: %d, ok: %d, failed: %d, skipped: %d, conflicts: %d, rows affected: %d\n",
+		r.Summary.Rows, r.Summary.OK, r.Summary.Failed, r.Summary.Skipped, r.Summary.Conflicts, r.Summary.RowsAffected)
+	if r.Summary.WriteBackFailed > 0 {
+		fmt.Fprintln(w, utils.Fatal(fmt.Sprintf("  results not written back to the sheet: %d", r.Summary.WriteBackFailed)))
+	}
+
+	actions := make([]string, 0, len(r.Summary.Actions))
+	for action := range r.Summary.Actions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Fprintf(w, "  %s: %d\n", action, r.Summary.Actions[action])
+	}
+}
@@ -0,0 +1,279 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*Status of a row*/
+const (
+	StatusOK      = "OK"
+	StatusFailed  = "FAILED"
+	StatusSkipped = "SKIPPED"
+)
+
+/*Actions taken on a table*/
+const (
+	ActionUpdate = "update"
+	ActionInsert = "insert"
+	ActionDelete = "delete"
+)
+
+/*Stages of a row import an error can happen in*/
+const (
+	StageParse      = "parse"
+	StageValidation = "validation"
+	// StageConflict is a row whose supplier was updated after the row was prepared
+	StageConflict = "conflict"
+	StageDB       = "db"
+	// StageWriteBack is a result that could not be written back to the sheet, the row itself is imported
+	StageWriteBack = "write-back"
+)
+
+/*Formats a report can be written in*/
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Report is the outcome of an import run, row by row
+type Report struct {
+	Source     string    `json:"source"`
+	DryRun     bool      `json:"dry_run"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Summary    Summary   `json:"summary"`
+	Rows       []*Row    `json:"rows"`
+}
+
+// Summary holds the totals of a run
+type Summary struct {
+	Rows      int `json:"rows"`
+	OK        int `json:"ok"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Conflicts int `json:"conflicts"`
+	// WriteBackFailed counts the rows whose result could not be written back to the sheet
+	WriteBackFailed int            `json:"write_back_failed"`
+	Actions         map[string]int `json:"actions"`
+	RowsAffected    int64          `json:"rows_affected"`
+}
+
+// Row is the outcome of a single sheet row
+type Row struct {
+	Row        int      `json:"row"`
+	SupplierID int64    `json:"supplier_id,omitempty"`
+	Status     string   `json:"status"`
+	Actions    []Action `json:"actions,omitempty"`
+	Changes    []Change `json:"changes,omitempty"`
+	Errors     []Error  `json:"errors,omitempty"`
+	// DeletedBy and DeleteReason record who soft-deleted the supplier and why
+	DeletedBy    string `json:"deleted_by,omitempty"`
+	DeleteReason string `json:"delete_reason,omitempty"`
+	// Warnings are the cells written with a loss, such as the decimals of a number rounded to fit its field
+	Warnings []string `json:"warnings,omitempty"`
+	// Conflicts are the records of the supplier updated after the row was prepared, written anyway on forced runs
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// Action is a statement run on a table for the row
+type Action struct {
+	Table        string   `json:"table"`
+	Action       string   `json:"action"`
+	RowsAffected int64    `json:"rows_affected"`
+	Fields       []string `json:"fields"`
+}
+
+// Change is a field whose stored value differs from the one written, on dry and committed runs alike
+type Change struct {
+	Table  string `json:"table"`
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s.%s: %q -> %q", c.Table, c.Field, c.Before, c.After)
+}
+
+// Error is a problem that stopped the row
+type Error struct {
+	Stage   string `json:"stage"`
+	Table   string `json:"table,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e Error) String() string {
+	if e.Table != "" {
+		return e.Stage + " " + e.Table + ": " + e.Message
+	}
+
+	return e.Stage + ": " + e.Message
+}
+
+func New(source string, dryRun bool) *Report {
+	return &Report{Source: source, DryRun: dryRun, StartedAt: time.Now()}
+}
+
+// NewRow adds the sheet row to the report and returns it to be filled in
+func (r *Report) NewRow(number int) *Row {
+	row := &Row{Row: number}
+	r.Rows = append(r.Rows, row)
+	return row
+}
+
+// AddAction records a statement run on a table
+func (row *Row) AddAction(table, action string, affected int64, fields []string) {
+	row.Actions = append(row.Actions, Action{Table: table, Action: action, RowsAffected: affected, Fields: fields})
+}
+
+// AddError records an error of the given stage, on the table if any; a nil error is ignored
+func (row *Row) AddError(stage, table string, err error) {
+	if err == nil {
+		return
+	}
+
+	row.Errors = append(row.Errors, Error{Stage: stage, Table: table, Message: err.Error()})
+}
+
+// Finish stamps the end of the run and computes the summary
+func (r *Report) Finish() {
+	r.FinishedAt = time.Now()
+	r.Summary = Summary{Rows: len(r.Rows), Actions: map[string]int{}}
+	for _, row := range r.Rows {
+		switch row.Status {
+		case StatusOK:
+			r.Summary.OK++
+		case StatusSkipped:
+			r.Summary.Skipped++
+		default:
+			r.Summary.Failed++
+		}
+
+		if len(row.Conflicts) > 0 {
+			r.Summary.Conflicts++
+		}
+
+		for _, e := range row.Errors {
+			if e.Stage == StageWriteBack {
+				r.Summary.WriteBackFailed++
+				break
+			}
+		}
+
+		for _, action := range row.Actions {
+			r.Summary.Actions[action.Table+":"+action.Action]++
+			r.Summary.RowsAffected += action.RowsAffected
+		}
+	}
+}
+
+// FileFormat returns the format a report is saved in: format, or the one of the file extension when format is empty
+func FileFormat(path, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	switch format {
+	case FormatJSON, FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q, expected %s or %s", format, FormatJSON, FormatCSV)
+	}
+}
+
+// Save writes the report to the file in the given format, or the one of the file extension when format is empty
+func (r *Report) Save(path, format string) (err error) {
+	if format, err = FileFormat(path, format); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if format == FormatCSV {
+		return r.WriteCSV(f)
+	}
+
+	return r.WriteJSON(f)
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes a line per action, or a single line for the rows without actions. The field changes of a table are
+// listed on the line of its first action, the ones of the tables without action on the first line.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"row", "supplier_id", "status", "table", "action", "rows_affected", "fields", "errors", "deleted_by", "delete_reason", "conflicts", "warnings", "changes"})
+	for _, row := range r.Rows {
+		errs := make([]string, 0, len(row.Errors))
+		for _, e := range row.Errors {
+			errs = append(errs, e.String())
+		}
+
+		line := []string{strconv.Itoa(row.Row), "", row.Status, "", "", "", "", strings.Join(errs, "\n"), row.DeletedBy, row.DeleteReason, strings.Join(row.Conflicts, "\n"), strings.Join(row.Warnings, "\n"), ""}
+		if row.SupplierID != 0 {
+			line[1] = strconv.FormatInt(row.SupplierID, 10)
+		}
+
+		changes := row.tableChanges()
+		if len(row.Actions) == 0 {
+			line[12] = strings.Join(changes[""], "\n")
+			_ = cw.Write(line)
+			continue
+		}
+
+		for i, action := range row.Actions {
+			line[3], line[4] = action.Table, action.Action
+			line[5] = strconv.FormatInt(action.RowsAffected, 10)
+			line[6] = strings.Join(action.Fields, "|")
+			lineChanges := changes[action.Table]
+			if i == 0 {
+				lineChanges = append(lineChanges, changes[""]...)
+			}
+			line[12] = strings.Join(lineChanges, "\n")
+			delete(changes, action.Table)
+			_ = cw.Write(line)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// tableChanges groups the field changes by table, those of the tables without action under ""
+func (row *Row) tableChanges() map[string][]string {
+	actionTables := map[string]bool{}
+	for _, action := range row.Actions {
+		actionTables[action.Table] = true
+	}
+
+	changes := map[string][]string{}
+	for _, change := range row.Changes {
+		table := change.Table
+		if !actionTables[table] {
+			table = ""
+		}
+		changes[table] = append(changes[table], change.String())
+	}
+
+	return changes
+}
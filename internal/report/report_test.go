@@ -0,0 +1,59 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	r := New("'To Update on DB'!A3:BA", false)
+	row := r.NewRow(3)
+	row.SupplierID, row.Status = 1, StatusOK
+	row.AddAction("suppliers", ActionUpdate, 1, []string{"company_name", "country"})
+	row.AddAction("supplier_details", ActionUpdate, 1, []string{"margin_in_percentage"})
+	row.AddAction("supplier_categories", ActionDelete, 1, []string{"deleted_at"})
+	row.AddAction("supplier_categories", ActionInsert, 1, []string{"supplier_id", "category_id", "created_at"})
+	row.Changes = []Change{
+		{Table: "suppliers", Field: "company_name", Before: "Acme", After: "Acme Toys"},
+		{Table: "supplier_details", Field: "margin_in_percentage", Before: "10", After: "NULL"},
+		{Table: "supplier_categories", Field: "category_id", Before: "3", After: "1"},
+		{Table: "suppliers", Field: "country", Before: "China", After: "Vietnam"},
+		{Table: "bank_account_details", Field: "bank_name", Before: "", After: "ICBC"},
+	}
+	skipped := r.NewRow(4)
+	skipped.Status = StatusSkipped
+	dryRun := r.NewRow(5)
+	dryRun.Status = StatusOK
+	dryRun.Changes = []Change{{Table: "suppliers", Field: "city", Before: "", After: "Shenzhen"}}
+	r.Finish()
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	lines, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"changes",
+		"suppliers.company_name: \"Acme\" -> \"Acme Toys\"\nsuppliers.country: \"China\" -> \"Vietnam\"\n" +
+			`bank_account_details.bank_name: "" -> "ICBC"`,
+		`supplier_details.margin_in_percentage: "10" -> "NULL"`,
+		`supplier_categories.category_id: "3" -> "1"`,
+		"",
+		"",
+		`suppliers.city: "" -> "Shenzhen"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("%d lines, want %d: %q", len(lines), len(want), lines)
+	}
+	for i, line := range lines {
+		if got := line[len(line)-1]; got != want[i] {
+			t.Errorf("line %d changes = %q, want %q", i, got, want[i])
+		}
+	}
+}
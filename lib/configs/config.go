@@ -23,30 +23,24 @@ type viperConfig struct {
 }
 
 // New creates and returns a new config instance
-// If there are any yaml file configurations, these files will be loaded in as environment variables
-func New() Config {
+// If configFile points to a yaml file, its configurations will be loaded in as environment variables
+func New(configFile string) Config {
 	c = &viperConfig{v: viper.New()}
 
-	args := os.Args[1:]
-	if len(args) > 0 {
-		configFile := args[0]
-		if strings.HasSuffix(configFile, ".yaml") {
-			c.filename = configFile
-			if _, err := os.Stat(configFile); err == nil {
-				configType := filepath.Ext(configFile)
-				configName := strings.TrimSuffix(configFile, configType)
+	if strings.HasSuffix(configFile, ".yaml") {
+		c.filename = configFile
+		if _, err := os.Stat(configFile); err == nil {
+			c.v.SetConfigFile(configFile)
+			c.v.SetConfigType(strings.TrimPrefix(filepath.Ext(configFile), "."))
 
-				c.v.SetConfigName(configName)
-				c.v.SetConfigType(configType[1:])
-				c.v.AddConfigPath(".")
-
-				err := c.v.ReadInConfig()
-				if err != nil {
-					log.Error().Err(err).Msg("Error reading config file")
-				}
-
-				log.Info().Msgf("Reading config file: %s", configFile)
+			err := c.v.ReadInConfig()
+			if err != nil {
+				log.Error().Err(err).Msg("Error reading config file")
 			}
+
+			log.Info().Msgf("Reading config file: %s", configFile)
+		} else {
+			log.Error().Err(err).Msgf("Config file not found: %s", configFile)
 		}
 	}
 
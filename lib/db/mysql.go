@@ -24,7 +24,8 @@ func Open(c configs.Config) *sql.DB {
 
 func open(user, pass, host, name string, maxConn int) *sql.DB {
 	driverName := "mysql"
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4,utf8&parseTime=True&loc=UTC", user, pass, host, name)
+	// clientFoundRows makes RowsAffected count the matched rows, so an UPDATE writing the stored values is not mistaken for a missing row
+	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4,utf8&parseTime=True&loc=UTC&clientFoundRows=true", user, pass, host, name)
 
 	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {